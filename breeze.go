@@ -2,6 +2,7 @@ package breeze
 
 import (
 	"errors"
+	"sort"
 )
 
 // breeze type
@@ -47,7 +48,7 @@ const (
 
 // configure
 var (
-	MaxWriteCount = 0 // TODO check circular reference
+	MaxWriteDepth = 1000 // default max nesting depth of messages, maps and arrays in writing
 	MaxElemSize   = 100000
 )
 
@@ -57,6 +58,9 @@ var (
 	ErrWrongSize = errors.New("breeze: read byte size not correct")
 	ErrNotEnough = errors.New("breeze: not enough bytes")
 	ErrOverflow  = errors.New("breeze: integer overflow")
+
+	ErrMaxDepth          = errors.New("breeze: write depth exceeds the max write depth")
+	ErrCircularReference = errors.New("breeze: circular reference found in writing")
 )
 
 // Message is a interface of breeze message. all breeze message must implement Message
@@ -79,6 +83,7 @@ type Context struct {
 	messageTypeRefCount int
 	messageTypeRefName  map[int]string
 	messageTypeRefIndex map[string]int
+	schemas             map[string]*Schema
 }
 
 func (c *Context) getMessageTypeName(index int) (name string) {
@@ -108,6 +113,43 @@ func (c *Context) putMessageType(name string) {
 	c.messageTypeRefIndex[name] = c.messageTypeRefCount
 }
 
+// truncateMessageType remove the message types which ref index is greater than count.
+// it is used to rollback the message types put by a failed encode or decode.
+func (c *Context) truncateMessageType(count int) {
+	for i := count + 1; i <= c.messageTypeRefCount; i++ {
+		name := c.messageTypeRefName[i]
+		delete(c.messageTypeRefName, i)
+		if c.messageTypeRefIndex[name] == i {
+			delete(c.messageTypeRefIndex, name)
+		}
+	}
+	if count < c.messageTypeRefCount {
+		c.messageTypeRefCount = count
+	}
+}
+
+// PutSchema put a schema into context, the schema can be found by name or alias
+func (c *Context) PutSchema(schema *Schema) {
+	if schema == nil {
+		return
+	}
+	if c.schemas == nil {
+		c.schemas = make(map[string]*Schema, DefaultSize)
+	}
+	c.schemas[schema.Name] = schema
+	if schema.Alias != "" {
+		c.schemas[schema.Alias] = schema
+	}
+}
+
+// GetSchema get a schema by message name or alias
+func (c *Context) GetSchema(name string) *Schema {
+	if c.schemas == nil {
+		return nil
+	}
+	return c.schemas[name]
+}
+
 // GenericMessage is a generic breeze message. it can receive any breeze message
 type GenericMessage struct {
 	Name   string
@@ -124,6 +166,17 @@ func (g *GenericMessage) GetAlias() string {
 // WriteTo write breeze message to breeze buffer.
 func (g *GenericMessage) WriteTo(buf *Buffer) error {
 	return WriteMessageWithoutType(buf, func(buf *Buffer) {
+		if buf.canonical {
+			indexes := make([]int, 0, len(g.fields))
+			for k := range g.fields {
+				indexes = append(indexes, k)
+			}
+			sort.Ints(indexes)
+			for _, k := range indexes {
+				WriteField(buf, k, g.fields[k])
+			}
+			return
+		}
 		for k, v := range g.fields {
 			WriteField(buf, k, v)
 		}
@@ -194,6 +247,7 @@ type Schema struct {
 	Alias         string
 	indexFieldMap map[int]*Field
 	nameFieldMap  map[string]*Field
+	enumValues    map[int]string // enum number -> enum value name, only for enum
 }
 
 // PutFields put a field into a schema
@@ -212,6 +266,16 @@ func (s *Schema) PutFields(fields ...*Field) {
 	}
 }
 
+// GetFields get all fields of schema ordered by field index
+func (s *Schema) GetFields() []*Field {
+	fields := make([]*Field, 0, len(s.indexFieldMap))
+	for _, f := range s.indexFieldMap {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Index < fields[j].Index })
+	return fields
+}
+
 // GetFieldByIndex get a message's field from schema by field index
 func (s *Schema) GetFieldByIndex(index int) *Field {
 	if s.indexFieldMap != nil {
@@ -228,6 +292,35 @@ func (s *Schema) GetFieldByName(name string) *Field {
 	return nil
 }
 
+// PutEnumValue put an enum value into the schema of enum. enum values are used to convert enums between numbers and names, such as in json.
+func (s *Schema) PutEnumValue(number int, name string) {
+	if s.enumValues == nil {
+		s.enumValues = make(map[int]string, DefaultSize)
+	}
+	s.enumValues[number] = name
+}
+
+// IsEnum check whether the schema has enum values
+func (s *Schema) IsEnum() bool {
+	return len(s.enumValues) > 0
+}
+
+// GetEnumName get the name of enum value by number
+func (s *Schema) GetEnumName(number int) (string, bool) {
+	name, ok := s.enumValues[number]
+	return name, ok
+}
+
+// GetEnumNumber get the number of enum value by name
+func (s *Schema) GetEnumNumber(name string) (int, bool) {
+	for number, n := range s.enumValues {
+		if n == name {
+			return number, true
+		}
+	}
+	return 0, false
+}
+
 // Field describes a message field, include field index, field name and field type
 type Field struct {
 	Index int
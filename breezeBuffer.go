@@ -14,6 +14,22 @@ type Buffer struct {
 	order   binary.ByteOrder
 	temp    []byte
 	context *Context
+	// write the schema of message before the message type at the first time the message type appears
+	writeSchema bool
+	// write map entries and generic message fields in sorted order, so equal values are written into identical bytes
+	canonical bool
+	// only read values which breeze types match the expected types, no implicit coercion between strings, numbers and bools
+	strict bool
+	// max nesting depth in writing, MaxWriteDepth is used if it is not positive
+	maxWriteDepth int
+	writeRefs     []writeRef // messages, maps and arrays being written, the length is the current write depth
+	writeErr      error      // the first error of nested writing, such as ErrMaxDepth
+	limits        *DecodeLimits
+	readDepth     int
+	readAlloc     int
+	// the message which fields will be read next, it is used to get the field path of read errors
+	readingName   string
+	readingSchema *Schema
 }
 
 // NewBuffer create A empty Buffer with initial size
@@ -151,11 +167,11 @@ func (b *Buffer) Bytes() []byte { return b.buf[:b.wpos] }
 
 // Read read buffer's byte to byte array. return value n is read size.
 func (b *Buffer) Read(p []byte) (n int, err error) {
-	if b.rpos >= len(b.buf) {
+	if b.rpos >= b.wpos {
 		return 0, io.EOF
 	}
 
-	n = copy(p, b.buf[b.rpos:])
+	n = copy(p, b.buf[b.rpos:b.wpos])
 	b.rpos += n
 	return n, nil
 }
@@ -165,7 +181,7 @@ func (b *Buffer) ReadFull(p []byte) error {
 	if b.Remain() < len(p) {
 		return ErrNotEnough
 	}
-	n := copy(p, b.buf[b.rpos:])
+	n := copy(p, b.buf[b.rpos:b.wpos])
 	if n < len(p) {
 		return ErrNotEnough
 	}
@@ -263,7 +279,7 @@ func (b *Buffer) Next(n int) ([]byte, error) {
 
 // ReadByte read a byte form buffer
 func (b *Buffer) ReadByte() (byte, error) {
-	if b.rpos >= len(b.buf) {
+	if b.rpos >= b.wpos {
 		return 0, io.EOF
 	}
 	c := b.buf[b.rpos]
@@ -275,6 +291,10 @@ func (b *Buffer) ReadByte() (byte, error) {
 func (b *Buffer) Reset() {
 	b.rpos = 0
 	b.wpos = 0
+	b.writeRefs = b.writeRefs[:0]
+	b.writeErr = nil
+	b.readDepth = 0
+	b.readAlloc = 0
 }
 
 // Remain is used in buffer read, it return a size of bytes the buffer remained
@@ -286,6 +306,31 @@ func (b *Buffer) Len() int { return b.wpos - 0 }
 // Cap return the capacity of the under byte buffer
 func (b *Buffer) Cap() int { return cap(b.buf) }
 
+// SetWriteSchema set whether write the schema of messages. if it is true, the schema of a message
+// will be written before the message type at the first time the message type appears in the buffer,
+// so the receiver can get fields by name from GenericMessage without any generated code.
+func (b *Buffer) SetWriteSchema(writeSchema bool) {
+	b.writeSchema = writeSchema
+}
+
+// SetStrict set whether read values in strict mode. in strict mode, a value which breeze type does not match the expected type
+// returns TypeMismatchError instead of being coerced, for example String("123") can not be read as int64 and Int32 can not be read as string.
+// integer types can still be read as each other if the value is in range, float types are same. see the coercion table in breezeCoerce.go
+func (b *Buffer) SetStrict(strict bool) {
+	b.strict = strict
+}
+
+// IsStrict check whether the buffer reads values in strict mode
+func (b *Buffer) IsStrict() bool {
+	return b.strict
+}
+
+// SetMaxWriteDepth set the max nesting depth of messages, maps and arrays in writing.
+// ErrMaxDepth will be returned if the depth exceeds it. MaxWriteDepth is used if depth is not positive.
+func (b *Buffer) SetMaxWriteDepth(depth int) {
+	b.maxWriteDepth = depth
+}
+
 // GetContext get breeze context
 func (b *Buffer) GetContext() *Context {
 	if b.context == nil {
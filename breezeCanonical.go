@@ -0,0 +1,149 @@
+package breeze
+
+import (
+	"reflect"
+	"sort"
+)
+
+// SetCanonical set whether write values in canonical mode. in canonical mode, map entries are written in ascending key order
+// and the fields of GenericMessage are written in ascending index order, so equal values are always written into identical bytes.
+// messages with their own WriteTo should check IsCanonical and write map entries in ascending key order too, as the generated codes do.
+func (b *Buffer) SetCanonical(canonical bool) {
+	b.canonical = canonical
+}
+
+// IsCanonical check whether the buffer writes values in canonical mode
+func (b *Buffer) IsCanonical() bool {
+	return b.canonical
+}
+
+// sortedMapKeys get the keys of map v in ascending order
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return compareKey(keys[i], keys[j]) < 0 })
+	return keys
+}
+
+// compareKey compare two map keys. keys of interface type are compared by the kind of the real values first,
+// so the keys of map[interface{}]interface{} such as 1 and "a" have a stable order.
+func compareKey(a, b reflect.Value) int {
+	if a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() { // nil interface is the smallest key
+		return compareInt(boolInt(a.IsValid()), boolInt(b.IsValid()))
+	}
+	if a.Kind() != b.Kind() {
+		return compareInt(int64(a.Kind()), int64(b.Kind()))
+	}
+	if a.Type() != b.Type() {
+		return compareString(a.Type().String(), b.Type().String())
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return compareString(a.String(), b.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareInt(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, y := a.Uint(), b.Uint()
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	case reflect.Float32, reflect.Float64:
+		x, y := a.Float(), b.Float()
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	case reflect.Bool:
+		return compareInt(boolInt(a.Bool()), boolInt(b.Bool()))
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return compareInt(boolInt(!a.IsNil()), boolInt(!b.IsNil()))
+		}
+		return compareKey(a.Elem(), b.Elem())
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if c := compareKey(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if c := compareKey(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareString(a, b string) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// rangeSortedMap is rangeMap in canonical mode
+func rangeSortedMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
+	for _, k := range sortedMapKeys(v) {
+		err = key.encode(buf, k, true)
+		if err != nil {
+			return err
+		}
+		err = value.encode(buf, v.MapIndex(k), true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rangeSortedPackedMap is rangePackedMap in canonical mode
+func rangeSortedPackedMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
+	for i, k := range sortedMapKeys(v) {
+		mv := v.MapIndex(k)
+		if i == 0 {
+			if err = key.writeType(buf, k); err != nil {
+				return err
+			}
+			if err = value.writeType(buf, mv); err != nil {
+				return err
+			}
+		}
+		err = key.encode(buf, k, false)
+		if err != nil {
+			return err
+		}
+		err = value.encode(buf, mv, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
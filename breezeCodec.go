@@ -0,0 +1,1047 @@
+package breeze
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// encodeFunc write a reflect value into buffer
+type encodeFunc func(buf *Buffer, rv reflect.Value, withType bool) error
+
+// typeFunc write the breeze type of a reflect value, only for packed map and packed array
+type typeFunc func(buf *Buffer, rv reflect.Value) error
+
+// decodeFunc read a value which breeze type is tp(and message name is name) from buffer
+type decodeFunc func(buf *Buffer, tp byte, name string) (interface{}, error)
+
+// setFunc read a value which breeze type is tp from buffer, and set it into rv
+type setFunc func(buf *Buffer, tp byte, name string, rv reflect.Value) error
+
+// the struct fields which index is less than maxDenseFieldIndex are found by index without map in reading
+const maxDenseFieldIndex = 128
+
+// codec is a compiled encoder and decoder of a go type. codecs are built once and cached by reflect.Type
+type codec struct {
+	encode    encodeFunc
+	writeType typeFunc
+	decode    decodeFunc
+	set       setFunc
+	fields    []*fieldCodec                             // field codecs of struct, in the order of structInfo.fields
+	read      func(buf *Buffer, rv reflect.Value) error // read the message fields into struct rv, the message type has been read
+}
+
+// fieldCodec is the compiled writer and reader of a struct field. the basic fields are written by the writers of their kinds,
+// so the nil and empty checks and the codec of field type are not dispatched at every write
+type fieldCodec struct {
+	*structField
+	codec *codec
+	write func(buf *Buffer, fv reflect.Value) error // write the field with index, nil values and omitted empty values are not written
+}
+
+var (
+	codecs    sync.Map // map[reflect.Type]*codec
+	codecLock sync.Mutex
+)
+
+func getCodec(t reflect.Type) *codec {
+	if c, ok := codecs.Load(t); ok {
+		return c.(*codec)
+	}
+	codecLock.Lock()
+	defer codecLock.Unlock()
+	building := make(map[reflect.Type]*codec, DefaultSize)
+	c := buildCodec(t, building)
+	// only store the codecs after all of them are built, because recursive types refer to the codecs in building
+	for bt, bc := range building {
+		codecs.Store(bt, bc)
+	}
+	return c
+}
+
+func buildCodec(t reflect.Type, building map[reflect.Type]*codec) *codec {
+	if c, ok := codecs.Load(t); ok {
+		return c.(*codec)
+	}
+	if c, ok := building[t]; ok {
+		return c
+	}
+	c := &codec{}
+	building[t] = c
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		return readValueDefault(buf, t, tp, name)
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if t.Implements(messageType) {
+			buildMessageCodec(c, t)
+		} else {
+			buildPtrCodec(c, t, building)
+		}
+	case reflect.Interface:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			if rv.IsNil() {
+				return writeNull(buf, withType)
+			}
+			rv = rv.Elem()
+			return getCodec(rv.Type()).encode(buf, rv, withType)
+		}
+		c.writeType = func(buf *Buffer, rv reflect.Value) error {
+			if rv.IsNil() {
+				return errors.New("breeze: unsupported type invalid")
+			}
+			rv = rv.Elem()
+			return getCodec(rv.Type()).writeType(buf, rv)
+		}
+	case reflect.String:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteString(buf, rv.String(), withType)
+			return nil
+		}
+		c.writeType = simpleType(StringType)
+	case reflect.Bool:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteBool(buf, rv.Bool(), withType)
+			return nil
+		}
+		c.writeType = simpleType(TrueType)
+	case reflect.Int, reflect.Int32:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteInt32(buf, int32(rv.Int()), withType)
+			return nil
+		}
+		c.writeType = simpleType(Int32Type)
+	case reflect.Int64:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteInt64(buf, rv.Int(), withType)
+			return nil
+		}
+		c.writeType = simpleType(Int64Type)
+	case reflect.Int16:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteInt16(buf, int16(rv.Int()), withType)
+			return nil
+		}
+		c.writeType = simpleType(Int16Type)
+	// unsigned integers are written as the wider signed integers, so they are not overflowed.
+	// uint and uint64 are written as int64, the values over math.MaxInt64 can not be written.
+	case reflect.Uint16:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteInt32(buf, int32(rv.Uint()), withType)
+			return nil
+		}
+		c.writeType = simpleType(Int32Type)
+	case reflect.Uint32:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteInt64(buf, int64(rv.Uint()), withType)
+			return nil
+		}
+		c.writeType = simpleType(Int64Type)
+	case reflect.Uint, reflect.Uint64:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			u := rv.Uint()
+			if u > math.MaxInt64 {
+				return &OverflowError{Offset: buf.GetWPos(), Expected: "int64", Actual: t.String(), Value: strconv.FormatUint(u, 10)}
+			}
+			WriteInt64(buf, int64(u), withType)
+			return nil
+		}
+		c.writeType = simpleType(Int64Type)
+	case reflect.Uint8:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteByte(buf, byte(rv.Uint()), withType)
+			return nil
+		}
+		c.writeType = simpleType(ByteType)
+	case reflect.Float32:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteFloat32(buf, float32(rv.Float()), withType)
+			return nil
+		}
+		c.writeType = simpleType(Float32Type)
+	case reflect.Float64:
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			WriteFloat64(buf, rv.Float(), withType)
+			return nil
+		}
+		c.writeType = simpleType(Float64Type)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+				WriteBytes(buf, rv.Bytes(), withType)
+				return nil
+			}
+			c.writeType = simpleType(BytesType)
+		} else {
+			buildArrayCodec(c, t, building)
+		}
+	case reflect.Map:
+		buildMapCodec(c, t, building)
+	case reflect.Struct:
+		if t == rawValueType {
+			buildRawValueCodec(c)
+		} else {
+			buildStructCodec(c, t, building)
+		}
+	default:
+		err := errors.New("breeze: unsupported type " + t.Kind().String())
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			return err
+		}
+		c.writeType = func(buf *Buffer, rv reflect.Value) error {
+			return err
+		}
+	}
+	if c.set == nil { // maps, slices and struct pointers have their own set functions
+		c.set = getFastSetFunc(t, c)
+	}
+	return c
+}
+
+// getDecodeSetFunc get a set function which decodes the value and converts it to type t
+func getDecodeSetFunc(t reflect.Type, c *codec) setFunc {
+	return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+		v, err := c.decode(buf, tp, name)
+		if err != nil {
+			return err
+		}
+		nv, err := toValue(buf, v, t)
+		if err != nil {
+			return err
+		}
+		rv.Set(nv)
+		return nil
+	}
+}
+
+// getFastSetFunc get a set function which can set the basic types without boxing when the breeze type matches the go type
+func getFastSetFunc(t reflect.Type, c *codec) setFunc {
+	set := getDecodeSetFunc(t, c)
+	switch t.Kind() {
+	case reflect.String:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp <= DirectStringMaxType {
+				bytes, err := readStringBytes(buf, uint64(tp))
+				if err != nil {
+					return err
+				}
+				rv.SetString(string(bytes))
+				return nil
+			}
+			if tp == StringType {
+				s, err := ReadStringWithoutType(buf)
+				if err != nil {
+					return err
+				}
+				rv.SetString(s)
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Int, reflect.Int32:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp >= DirectInt32MinType && tp <= DirectInt32MaxType {
+				rv.SetInt(int64(int32(tp) - Int32Zero))
+				return nil
+			}
+			if tp == Int32Type {
+				i, err := ReadInt32WithoutType(buf)
+				if err != nil {
+					return err
+				}
+				rv.SetInt(int64(i))
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Int64:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp >= DirectInt64MinType && tp <= DirectInt64MaxType {
+				rv.SetInt(int64(tp) - Int64Zero)
+				return nil
+			}
+			if tp == Int64Type {
+				i, err := ReadInt64WithoutType(buf)
+				if err != nil {
+					return err
+				}
+				rv.SetInt(i)
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Int16:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp == Int16Type {
+				i, err := ReadInt16WithoutType(buf)
+				if err != nil {
+					return err
+				}
+				rv.SetInt(int64(i))
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Uint8:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp == ByteType {
+				b, err := buf.ReadByte()
+				if err != nil {
+					return err
+				}
+				rv.SetUint(uint64(b))
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Bool:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp == TrueType || tp == FalseType {
+				rv.SetBool(tp == TrueType)
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp == Float32Type && t.Kind() == reflect.Float32 {
+				f, err := ReadFloat32WithoutType(buf)
+				if err != nil {
+					return err
+				}
+				rv.SetFloat(float64(f))
+				return nil
+			}
+			if tp == Float64Type && t.Kind() == reflect.Float64 {
+				f, err := ReadFloat64WithoutType(buf)
+				if err != nil {
+					return err
+				}
+				rv.SetFloat(f)
+				return nil
+			}
+			return set(buf, tp, name, rv)
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+				if tp == BytesType {
+					bytes, err := ReadBytesWithoutType(buf)
+					if err != nil {
+						return err
+					}
+					rv.SetBytes(bytes)
+					return nil
+				}
+				return set(buf, tp, name, rv)
+			}
+		}
+	}
+	return set
+}
+
+func simpleType(tp byte) typeFunc {
+	return func(buf *Buffer, rv reflect.Value) error {
+		buf.WriteByte(tp)
+		return nil
+	}
+}
+
+func buildMessageCodec(c *codec, t reflect.Type) {
+	c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+		return writeMessage(buf, rv.Interface().(Message), withType)
+	}
+	c.writeType = func(buf *Buffer, rv reflect.Value) error {
+		msg := rv.Interface().(Message)
+		writeMessageType(buf, msg.GetName(), msg.GetSchema())
+		return nil
+	}
+	isEnum := t.Implements(enumType)
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		if tp != MessageType {
+			return readValueDefault(buf, t, tp, name)
+		}
+		nv := reflect.New(t.Elem()).Interface()
+		if isEnum {
+			return nv.(Enum).ReadEnum(buf, true)
+		}
+		setReadingMessage(buf, name, nv.(Message).GetSchema())
+		err := nv.(Message).ReadFrom(buf)
+		if err != nil {
+			return nil, err
+		}
+		return nv, nil
+	}
+}
+
+func buildPtrCodec(c *codec, t reflect.Type, building map[reflect.Type]*codec) {
+	elem := buildCodec(t.Elem(), building)
+	// only the pointers of the values which can contain themselves are tracked, a pointer to a basic field may have the same
+	// address as the struct being written
+	var recursive bool
+	switch t.Elem().Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Interface:
+		recursive = true
+	}
+	c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+		if rv.IsNil() {
+			return writeNull(buf, withType)
+		}
+		var ptr uintptr
+		if recursive {
+			ptr = rv.Pointer()
+		}
+		if err := enterWrite(buf, ptr, t, 0); err != nil {
+			return err
+		}
+		err := elem.encode(buf, rv.Elem(), withType)
+		if lerr := leaveWrite(buf); err == nil {
+			err = lerr
+		}
+		return err
+	}
+	c.writeType = func(buf *Buffer, rv reflect.Value) error {
+		if rv.IsNil() {
+			return elem.writeType(buf, reflect.Zero(t.Elem()))
+		}
+		return elem.writeType(buf, rv.Elem())
+	}
+	if t.Elem().Kind() == reflect.Struct && t.Elem() != rawValueType {
+		c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+			if tp != MessageType {
+				return readValueDefault(buf, t, tp, name)
+			}
+			nv := reflect.New(t.Elem())
+			err := elem.read(buf, nv.Elem())
+			if err != nil {
+				return nil, err
+			}
+			return nv.Interface(), nil
+		}
+		set := getDecodeSetFunc(t, c)
+		c.set = func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+			if tp != MessageType {
+				return set(buf, tp, name, rv)
+			}
+			nv := reflect.New(t.Elem())
+			if err := elem.read(buf, nv.Elem()); err != nil {
+				return err
+			}
+			rv.Set(nv)
+			return nil
+		}
+		return
+	}
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		if tp == NullType { // nil pointer
+			return nil, nil
+		}
+		nv := reflect.New(t.Elem())
+		if err := elem.set(buf, tp, name, nv.Elem()); err != nil {
+			return nil, err
+		}
+		return nv.Interface(), nil
+	}
+}
+
+// writeNull write nil pointer or nil interface as NullType. nil can not be written without type, such as the elements of packed array
+func writeNull(buf *Buffer, withType bool) error {
+	if !withType {
+		return errors.New("breeze: nil value can not be written without type")
+	}
+	buf.WriteByte(NullType)
+	return nil
+}
+
+func buildArrayCodec(c *codec, t reflect.Type, building map[reflect.Type]*codec) {
+	elem := buildCodec(t.Elem(), building)
+	if elemsFunc := getArrayElemsFunc(t); elemsFunc != nil {
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			if withType {
+				WritePackedArrayType(buf)
+			}
+			size := rv.Len()
+			buf.WriteVarInt(uint64(size))
+			if size > 0 {
+				elemsFunc(buf, rv)
+			}
+			return nil
+		}
+		c.writeType = simpleType(PackedArrayType)
+	} else if canPackArray(t) {
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			if withType {
+				WritePackedArrayType(buf)
+			}
+			size := rv.Len()
+			buf.WriteVarInt(uint64(size))
+			return writeNested(buf, rv, func() error {
+				for i := 0; i < size; i++ {
+					ev := rv.Index(i)
+					if i == 0 {
+						if err := elem.writeType(buf, ev); err != nil {
+							return err
+						}
+					}
+					if err := elem.encode(buf, ev, false); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		c.writeType = simpleType(PackedArrayType)
+	} else {
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			if withType {
+				buf.WriteByte(ArrayType)
+			}
+			size := rv.Len()
+			buf.WriteVarInt(uint64(size))
+			return writeNested(buf, rv, func() error {
+				for i := 0; i < size; i++ {
+					if err := elem.encode(buf, rv.Index(i), true); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		c.writeType = simpleType(ArrayType)
+	}
+	elemsType, elemsRead := getArrayReadFunc(t)
+	// read an array which breeze type is tp into rv, the empty array is read as nil slice
+	read := func(buf *Buffer, tp byte, rv reflect.Value) error {
+		size, err := ReadPackedSize(buf, false)
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			rv.Set(reflect.Zero(t))
+			return nil
+		}
+		if err = enterRead(buf); err != nil {
+			return err
+		}
+		defer leaveRead(buf)
+		isPacked := tp == PackedArrayType
+		var name string
+		if isPacked {
+			tp, name, err = readType(buf)
+			if err != nil {
+				return err
+			}
+		}
+		if isPacked && elemsRead != nil && tp == elemsType && rv.CanAddr() {
+			return elemsRead(buf, size, rv)
+		}
+		sv := reflect.MakeSlice(t, size, size)
+		etp := tp
+		for i := 0; i < size; i++ {
+			if isPacked {
+				etp, err = packedElemType(buf, tp)
+			} else {
+				etp, name, err = readType(buf)
+			}
+			if err != nil {
+				return err
+			}
+			err = elem.set(buf, etp, name, sv.Index(i))
+			if err != nil {
+				return withPath(err, indexSegment(i))
+			}
+		}
+		rv.Set(sv)
+		return nil
+	}
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		if tp != ArrayType && tp != PackedArrayType {
+			return readValueDefault(buf, t, tp, name)
+		}
+		rv := reflect.New(t).Elem()
+		if err := read(buf, tp, rv); err != nil || rv.IsNil() {
+			return nil, err
+		}
+		return rv.Interface(), nil
+	}
+	set := getDecodeSetFunc(t, c)
+	c.set = func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+		if tp != ArrayType && tp != PackedArrayType {
+			return set(buf, tp, name, rv)
+		}
+		return read(buf, tp, rv)
+	}
+}
+
+func buildMapCodec(c *codec, t reflect.Type, building map[reflect.Type]*codec) {
+	key := buildCodec(t.Key(), building)
+	value := buildCodec(t.Elem(), building)
+	isInterfaceKey := t.Key().Kind() == reflect.Interface
+	if canPackMap(t) {
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			if withType {
+				WritePackedMapType(buf)
+			}
+			buf.WriteVarInt(uint64(rv.Len()))
+			return writeNested(buf, rv, func() error {
+				if buf.canonical {
+					return rangeSortedPackedMap(buf, rv, key, value)
+				}
+				return rangePackedMap(buf, rv, key, value)
+			})
+		}
+		c.writeType = simpleType(PackedMapType)
+	} else {
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			if withType {
+				buf.WriteByte(MapType)
+			}
+			buf.WriteVarInt(uint64(rv.Len()))
+			return writeNested(buf, rv, func() error {
+				if buf.canonical {
+					return rangeSortedMap(buf, rv, key, value)
+				}
+				return rangeMap(buf, rv, key, value)
+			})
+		}
+		c.writeType = simpleType(MapType)
+	}
+	// read a map which breeze type is tp into rv, the empty map is read as nil map
+	read := func(buf *Buffer, tp byte, rv reflect.Value) error {
+		size, err := ReadPackedSize(buf, false)
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			rv.Set(reflect.Zero(t))
+			return nil
+		}
+		if err = enterRead(buf); err != nil {
+			return err
+		}
+		defer leaveRead(buf)
+		isPacked := tp == PackedMapType
+		var ktp, vtp byte
+		var kn, vn string
+		if isPacked {
+			ktp, kn, err = readType(buf)
+			if err != nil {
+				return err
+			}
+			vtp, vn, err = readType(buf)
+			if err != nil {
+				return err
+			}
+		}
+		mv := reflect.MakeMapWithSize(t, size)
+		pool, entry := getMapEntry(t)
+		defer putMapEntry(pool, entry)
+		kv, vv := entry.key, entry.value
+		for i := 0; i < size; i++ {
+			etp := ktp
+			if isPacked {
+				etp, err = packedElemType(buf, ktp)
+			} else {
+				etp, kn, err = readType(buf)
+			}
+			if err != nil {
+				return err
+			}
+			err = key.set(buf, etp, kn, kv)
+			if err != nil {
+				return err
+			}
+			if isPacked {
+				etp, err = packedElemType(buf, vtp)
+			} else {
+				etp, vn, err = readType(buf)
+			}
+			if err != nil {
+				return err
+			}
+			err = value.set(buf, etp, vn, vv)
+			if err != nil {
+				return withPath(err, keySegment(kv.Interface()))
+			}
+			if isInterfaceKey {
+				if err = checkMapKey(kv.Interface()); err != nil {
+					return err
+				}
+			}
+			mv.SetMapIndex(kv, vv)
+		}
+		rv.Set(mv)
+		return nil
+	}
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		if tp != MapType && tp != PackedMapType {
+			return readValueDefault(buf, t, tp, name)
+		}
+		rv := reflect.New(t).Elem()
+		if err := read(buf, tp, rv); err != nil || rv.IsNil() {
+			return nil, err
+		}
+		return rv.Interface(), nil
+	}
+	set := getDecodeSetFunc(t, c)
+	c.set = func(buf *Buffer, tp byte, name string, rv reflect.Value) error {
+		if tp != MapType && tp != PackedMapType {
+			return set(buf, tp, name, rv)
+		}
+		return read(buf, tp, rv)
+	}
+}
+
+func buildStructCodec(c *codec, t reflect.Type, building map[reflect.Type]*codec) {
+	info, err := getStructInfo(t)
+	if err != nil {
+		c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+			return err
+		}
+		c.writeType = func(buf *Buffer, rv reflect.Value) error {
+			return err
+		}
+		c.read = func(buf *Buffer, rv reflect.Value) error {
+			return err
+		}
+		return
+	}
+	c.fields = make([]*fieldCodec, 0, len(info.fields))
+	for _, f := range info.fields {
+		ft := t.Field(f.fieldNum).Type
+		fc := &fieldCodec{structField: f, codec: buildCodec(ft, building)}
+		fc.write = getFieldWriteFunc(fc, ft)
+		c.fields = append(c.fields, fc)
+	}
+	c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+		if withType {
+			writeMessageType(buf, info.name, info.schema)
+		}
+		pos := skipLength(buf)
+		for _, f := range c.fields {
+			if err := f.write(buf, rv.Field(f.fieldNum)); err != nil {
+				return err
+			}
+		}
+		writeLength(buf, pos)
+		return nil
+	}
+	// the fields with small indexes are found by slice, the others by map
+	var indexFields []*fieldCodec
+	for _, f := range c.fields {
+		if f.index < maxDenseFieldIndex {
+			for len(indexFields) <= f.index {
+				indexFields = append(indexFields, nil)
+			}
+			indexFields[f.index] = f
+		}
+	}
+	c.read = func(buf *Buffer, rv reflect.Value) error {
+		setReadingMessage(buf, info.name, info.schema)
+		return ReadMessageField(buf, func(buf *Buffer, index int) error {
+			var f *fieldCodec
+			if index >= 0 && index < len(indexFields) {
+				f = indexFields[index]
+			} else if sf := info.indexFields[index]; sf != nil {
+				f = c.fields[sf.num]
+			}
+			if f == nil { // skip unknown field
+				return SkipValue(buf)
+			}
+			tp, name, err := readType(buf)
+			if err != nil {
+				return err
+			}
+			return f.codec.set(buf, tp, name, rv.Field(f.fieldNum))
+		})
+	}
+	c.writeType = func(buf *Buffer, rv reflect.Value) error {
+		writeMessageType(buf, info.name, info.schema)
+		return nil
+	}
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		if tp != MessageType {
+			return readValueDefault(buf, t, tp, name)
+		}
+		nv := reflect.New(t).Elem()
+		err := c.read(buf, nv)
+		if err != nil {
+			return nil, err
+		}
+		return nv.Interface(), nil
+	}
+}
+
+// getFieldWriteFunc get the write function of a struct field which type is t. the fields of basic types are checked and
+// written by their kinds directly, the others are written by the codec of field type
+func getFieldWriteFunc(f *fieldCodec, t reflect.Type) func(buf *Buffer, fv reflect.Value) error {
+	index, omitEmpty := uint64(f.index), f.omitEmpty
+	switch t.Kind() {
+	case reflect.String:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if s := fv.String(); !omitEmpty || s != "" {
+				buf.WriteVarInt(index)
+				WriteString(buf, s, true)
+			}
+			return nil
+		}
+	case reflect.Bool:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if b := fv.Bool(); !omitEmpty || b {
+				buf.WriteVarInt(index)
+				WriteBool(buf, b, true)
+			}
+			return nil
+		}
+	case reflect.Int, reflect.Int32:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if i := fv.Int(); !omitEmpty || i != 0 {
+				buf.WriteVarInt(index)
+				WriteInt32(buf, int32(i), true)
+			}
+			return nil
+		}
+	case reflect.Int64:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if i := fv.Int(); !omitEmpty || i != 0 {
+				buf.WriteVarInt(index)
+				WriteInt64(buf, i, true)
+			}
+			return nil
+		}
+	case reflect.Uint8:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if u := fv.Uint(); !omitEmpty || u != 0 {
+				buf.WriteVarInt(index)
+				WriteByte(buf, byte(u), true)
+			}
+			return nil
+		}
+	case reflect.Float32:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if v := fv.Float(); !omitEmpty || v != 0 {
+				buf.WriteVarInt(index)
+				WriteFloat32(buf, float32(v), true)
+			}
+			return nil
+		}
+	case reflect.Float64:
+		return func(buf *Buffer, fv reflect.Value) error {
+			if v := fv.Float(); !omitEmpty || v != 0 {
+				buf.WriteVarInt(index)
+				WriteFloat64(buf, v, true)
+			}
+			return nil
+		}
+	case reflect.Slice, reflect.Map:
+		c := f.codec
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return func(buf *Buffer, fv reflect.Value) error {
+				if b := fv.Bytes(); b != nil && (!omitEmpty || len(b) > 0) {
+					buf.WriteVarInt(index)
+					WriteBytes(buf, b, true)
+				}
+				return nil
+			}
+		}
+		return func(buf *Buffer, fv reflect.Value) error {
+			if fv.IsNil() || (omitEmpty && fv.Len() == 0) {
+				return nil
+			}
+			buf.WriteVarInt(index)
+			return c.encode(buf, fv, true)
+		}
+	case reflect.Ptr, reflect.Interface:
+		c := f.codec
+		return func(buf *Buffer, fv reflect.Value) error {
+			if fv.IsNil() {
+				return nil
+			}
+			buf.WriteVarInt(index)
+			return c.encode(buf, fv, true)
+		}
+	}
+	c := f.codec
+	return func(buf *Buffer, fv reflect.Value) error {
+		if isNilValue(fv) || (omitEmpty && isEmptyValue(fv)) {
+			return nil
+		}
+		buf.WriteVarInt(index)
+		return c.encode(buf, fv, true)
+	}
+}
+
+// mapEntry is the reusable key and value of map iterating
+type mapEntry struct {
+	key   reflect.Value
+	value reflect.Value
+}
+
+var mapEntryPools sync.Map // map[reflect.Type]*sync.Pool
+
+// getMapEntry get a reusable entry of map type t, it should be put back by putMapEntry
+func getMapEntry(t reflect.Type) (*sync.Pool, *mapEntry) {
+	p, ok := mapEntryPools.Load(t)
+	if !ok {
+		p, _ = mapEntryPools.LoadOrStore(t, &sync.Pool{New: func() interface{} {
+			return &mapEntry{key: reflect.New(t.Key()).Elem(), value: reflect.New(t.Elem()).Elem()}
+		}})
+	}
+	pool := p.(*sync.Pool)
+	return pool, pool.Get().(*mapEntry)
+}
+
+// putMapEntry clear the entry so the map elements are not kept by pool, and put it back
+func putMapEntry(pool *sync.Pool, entry *mapEntry) {
+	entry.key.Set(reflect.Zero(entry.key.Type()))
+	entry.value.Set(reflect.Zero(entry.value.Type()))
+	pool.Put(entry)
+}
+
+// writeNested write the elements of a map or slice with depth and circular reference checking
+func writeNested(buf *Buffer, rv reflect.Value, f func() error) error {
+	var ptr uintptr
+	var size int
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		ptr, size = rv.Pointer(), rv.Len()
+	}
+	if err := enterWrite(buf, ptr, rv.Type(), size); err != nil {
+		return err
+	}
+	err := f()
+	if lerr := leaveWrite(buf); err == nil {
+		err = lerr
+	}
+	return err
+}
+
+// getArrayElemsFunc get a fast write function for the slices of basic types. the addressable slices of exact basic types
+// are written by the typed writers without reflection of the elements
+func getArrayElemsFunc(t reflect.Type) func(buf *Buffer, rv reflect.Value) {
+	switch t.Elem().Kind() {
+	case reflect.String:
+		return func(buf *Buffer, rv reflect.Value) {
+			if t == stringSliceType && rv.CanAddr() {
+				WriteStringArrayElems(buf, *rv.Addr().Interface().(*[]string))
+				return
+			}
+			WriteStringType(buf)
+			for i := 0; i < rv.Len(); i++ {
+				WriteString(buf, rv.Index(i).String(), false)
+			}
+		}
+	case reflect.Int32:
+		return func(buf *Buffer, rv reflect.Value) {
+			if t == int32SliceType && rv.CanAddr() {
+				WriteInt32ArrayElems(buf, *rv.Addr().Interface().(*[]int32))
+				return
+			}
+			WriteInt32Type(buf)
+			for i := 0; i < rv.Len(); i++ {
+				WriteInt32(buf, int32(rv.Index(i).Int()), false)
+			}
+		}
+	case reflect.Int64:
+		return func(buf *Buffer, rv reflect.Value) {
+			if t == int64SliceType && rv.CanAddr() {
+				WriteInt64ArrayElems(buf, *rv.Addr().Interface().(*[]int64))
+				return
+			}
+			WriteInt64Type(buf)
+			for i := 0; i < rv.Len(); i++ {
+				WriteInt64(buf, rv.Index(i).Int(), false)
+			}
+		}
+	}
+	return nil
+}
+
+// getArrayReadFunc get a fast read function for the slices of basic types, it reads the elements of packed array which
+// element type is tp into addressable rv. read is nil if the slice type has no fast read function
+func getArrayReadFunc(t reflect.Type) (tp byte, read func(buf *Buffer, size int, rv reflect.Value) error) {
+	switch t {
+	case stringSliceType:
+		return StringType, func(buf *Buffer, size int, rv reflect.Value) error {
+			a := make([]string, size)
+			for i := range a {
+				s, err := ReadStringWithoutType(buf)
+				if err != nil {
+					return withPath(err, indexSegment(i))
+				}
+				a[i] = s
+			}
+			*rv.Addr().Interface().(*[]string) = a
+			return nil
+		}
+	case int32SliceType:
+		return Int32Type, func(buf *Buffer, size int, rv reflect.Value) error {
+			a := make([]int32, size)
+			for i := range a {
+				i32, err := ReadInt32WithoutType(buf)
+				if err != nil {
+					return withPath(err, indexSegment(i))
+				}
+				a[i] = i32
+			}
+			*rv.Addr().Interface().(*[]int32) = a
+			return nil
+		}
+	case int64SliceType:
+		return Int64Type, func(buf *Buffer, size int, rv reflect.Value) error {
+			a := make([]int64, size)
+			for i := range a {
+				i64, err := ReadInt64WithoutType(buf)
+				if err != nil {
+					return withPath(err, indexSegment(i))
+				}
+				a[i] = i64
+			}
+			*rv.Addr().Interface().(*[]int64) = a
+			return nil
+		}
+	}
+	return 0, nil
+}
+
+// toValue convert a read value to the reflect value of type t
+func toValue(buf *Buffer, v interface{}, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type() == t || rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	if rv.Kind() == t.Kind() && rv.Type().ConvertibleTo(t) {
+		return rv.Convert(t), nil
+	}
+	return rv, newTypeMismatchError(buf, t.String(), rv.Type().String())
+}
+
+func canPackArray(t reflect.Type) bool {
+	return canPackType(t.Elem())
+}
+
+func canPackMap(t reflect.Type) bool {
+	return canPackType(t.Key()) && canPackType(t.Elem())
+}
+
+// canPackType check whether the values of type t have the same breeze type
+func canPackType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Interface && t != rawValueType
+}
+
+var (
+	messageType     = reflect.TypeOf((*Message)(nil)).Elem()
+	enumType        = reflect.TypeOf((*Enum)(nil)).Elem()
+	stringSliceType = reflect.TypeOf([]string(nil))
+	int32SliceType  = reflect.TypeOf([]int32(nil))
+	int64SliceType  = reflect.TypeOf([]int64(nil))
+)
@@ -0,0 +1,360 @@
+package breeze
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+/*
+The scalar values are coerced to the expected go types by the following table, the direct forms of
+string, int32 and int64 are same as the non-direct forms. other conversions return TypeMismatchError,
+and the values out of the range of the expected type return OverflowError.
+
+	expected          breeze types
+	bool              True, False, String("true", "false", "1", "0", ...)
+	string            String, Byte, Int16, Int32, Int64, Float32, Float64, True, False
+	[]byte            Bytes, String
+	integers          Byte, Int16, Int32, Int64, String(integer)
+	float32, float64  Float32, Float64, Byte, Int16, Int32, Int64, String(number), integers must be exactly representable
+	interface{}       all scalar types, the value of the breeze type is used, Int32 is read as int
+
+the integers are byte, int16, uint16, int32, uint32, int, uint, int64 and uint64.
+
+In strict mode (Buffer.SetStrict), only the conversions between the same kind of values are allowed:
+
+	expected          breeze types
+	bool              True, False
+	string            String
+	[]byte            Bytes
+	integers          Byte, Int16, Int32, Int64
+	float32, float64  Float32, Float64
+	interface{}       all scalar types
+*/
+
+// scalar is a scalar breeze value. tp is the breeze type, the direct types are normalized to StringType, Int32Type and Int64Type
+type scalar struct {
+	tp byte
+	s  string  // StringType
+	b  []byte  // BytesType
+	i  int64   // ByteType, Int16Type, Int32Type, Int64Type
+	f  float64 // Float32Type, Float64Type
+}
+
+// isScalarType check whether tp is the type of a scalar breeze value
+func isScalarType(tp byte) bool {
+	return tp <= Int64Type || (tp >= TrueType && tp <= Float64Type)
+}
+
+// readScalar read a scalar value which breeze type is tp
+func readScalar(buf *Buffer, tp byte) (v scalar, err error) {
+	v.tp = tp
+	switch {
+	case tp <= DirectStringMaxType:
+		var bytes []byte
+		if bytes, err = readStringBytes(buf, uint64(tp)); err == nil {
+			v.s = string(bytes)
+		}
+		v.tp = StringType
+	case tp == StringType:
+		v.s, err = ReadStringWithoutType(buf)
+	case tp >= DirectInt32MinType && tp <= DirectInt32MaxType:
+		v.i = int64(int32(tp) - Int32Zero)
+		v.tp = Int32Type
+	case tp == Int32Type:
+		var i int32
+		i, err = ReadInt32WithoutType(buf)
+		v.i = int64(i)
+	case tp >= DirectInt64MinType && tp <= DirectInt64MaxType:
+		v.i = int64(tp) - Int64Zero
+		v.tp = Int64Type
+	case tp == Int64Type:
+		v.i, err = ReadInt64WithoutType(buf)
+	case tp == TrueType || tp == FalseType:
+	case tp == ByteType:
+		var b byte
+		b, err = buf.ReadByte()
+		v.i = int64(b)
+	case tp == BytesType:
+		v.b, err = ReadBytesWithoutType(buf)
+	case tp == Int16Type:
+		var i int16
+		i, err = ReadInt16WithoutType(buf)
+		v.i = int64(i)
+	case tp == Float32Type:
+		var f float32
+		f, err = ReadFloat32WithoutType(buf)
+		v.f = float64(f)
+	case tp == Float64Type:
+		v.f, err = ReadFloat64WithoutType(buf)
+	default:
+		err = newUnknownTypeError(buf, "scalar", tp)
+	}
+	return v, err
+}
+
+// readScalarOf read a scalar value which breeze type is tp for the expected type. TypeMismatchError is returned if tp is not a scalar type
+func readScalarOf(buf *Buffer, tp byte, expected string) (scalar, error) {
+	if !isScalarType(tp) {
+		return scalar{}, newTypeMismatchError(buf, expected, TypeName(tp))
+	}
+	return readScalar(buf, tp)
+}
+
+// value get the go value of the breeze type
+func (v scalar) value() interface{} {
+	switch v.tp {
+	case StringType:
+		return v.s
+	case Int32Type:
+		return int32(v.i)
+	case Int64Type:
+		return v.i
+	case TrueType:
+		return true
+	case FalseType:
+		return false
+	case ByteType:
+		return byte(v.i)
+	case BytesType:
+		return v.b
+	case Int16Type:
+		return int16(v.i)
+	case Float32Type:
+		return float32(v.f)
+	}
+	return v.f
+}
+
+func (v scalar) isInt() bool {
+	return v.tp == Int32Type || v.tp == Int64Type || v.tp == Int16Type || v.tp == ByteType
+}
+
+func (v scalar) mismatch(buf *Buffer, expected string) error {
+	if v.tp == StringType {
+		return newTypeMismatchError(buf, expected, "String("+strconv.Quote(v.s)+")")
+	}
+	return newTypeMismatchError(buf, expected, TypeName(v.tp))
+}
+
+func (v scalar) toBool(buf *Buffer, expected string) (bool, error) {
+	switch v.tp {
+	case TrueType, FalseType:
+		return v.tp == TrueType, nil
+	case StringType:
+		if buf.strict {
+			break
+		}
+		if b, err := strconv.ParseBool(v.s); err == nil {
+			return b, nil
+		}
+	}
+	return false, v.mismatch(buf, expected)
+}
+
+func (v scalar) toString(buf *Buffer, expected string) (string, error) {
+	switch {
+	case v.tp == StringType:
+		return v.s, nil
+	case buf.strict:
+	case v.isInt():
+		return strconv.FormatInt(v.i, 10), nil
+	case v.tp == Float32Type:
+		return strconv.FormatFloat(v.f, 'f', -1, 32), nil
+	case v.tp == Float64Type:
+		return strconv.FormatFloat(v.f, 'f', -1, 64), nil
+	case v.tp == TrueType || v.tp == FalseType:
+		return strconv.FormatBool(v.tp == TrueType), nil
+	}
+	return "", v.mismatch(buf, expected)
+}
+
+func (v scalar) toBytes(buf *Buffer, expected string) ([]byte, error) {
+	switch v.tp {
+	case BytesType:
+		return v.b, nil
+	case StringType:
+		if !buf.strict {
+			return []byte(v.s), nil
+		}
+	}
+	return nil, v.mismatch(buf, expected)
+}
+
+// toInt get the integer in the range [min, max] of the expected type
+func (v scalar) toInt(buf *Buffer, expected string, min int64, max int64) (int64, error) {
+	i := v.i
+	switch {
+	case v.isInt():
+	case v.tp == StringType && !buf.strict:
+		var err error
+		if i, err = strconv.ParseInt(v.s, 10, 64); isRangeError(err) {
+			return 0, newOverflowError(buf, expected, TypeName(v.tp), v.s)
+		} else if err != nil {
+			return 0, v.mismatch(buf, expected)
+		}
+	default:
+		return 0, v.mismatch(buf, expected)
+	}
+	if err := checkIntRange(buf, i, min, max, expected, TypeName(v.tp)); err != nil {
+		return 0, err
+	}
+	return i, nil
+}
+
+// toFloat get the float of the expected type which bit size is bitSize
+func (v scalar) toFloat(buf *Buffer, expected string, bitSize int) (float64, error) {
+	f := v.f
+	switch {
+	case v.tp == Float32Type || v.tp == Float64Type:
+	case buf.strict:
+		return 0, v.mismatch(buf, expected)
+	case v.isInt():
+		f = float64(v.i)
+		if bitSize == 32 {
+			f = float64(float32(v.i))
+		}
+		if f >= math.MaxInt64 || int64(f) != v.i { // the integer can not be represented exactly
+			return 0, newOverflowError(buf, expected, TypeName(v.tp), v.i)
+		}
+		return f, nil
+	case v.tp == StringType:
+		var err error
+		if f, err = strconv.ParseFloat(v.s, 64); isRangeError(err) {
+			return 0, newOverflowError(buf, expected, TypeName(v.tp), v.s)
+		} else if err != nil {
+			return 0, v.mismatch(buf, expected)
+		}
+	default:
+		return 0, v.mismatch(buf, expected)
+	}
+	if bitSize == 32 && math.IsInf(float64(float32(f)), 0) && !math.IsInf(f, 0) {
+		if v.tp == StringType {
+			return 0, newOverflowError(buf, expected, TypeName(v.tp), v.s)
+		}
+		return 0, newOverflowError(buf, expected, TypeName(v.tp), f)
+	}
+	return f, nil
+}
+
+// coerce convert the scalar value to go type t by the coercion table. the result is the basic type of t's kind
+func (v scalar) coerce(buf *Buffer, t reflect.Type) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.Interface:
+		if v.tp == Int32Type { // for compatible with other language, int32 is regarded as int
+			return int(v.i), nil
+		}
+		return v.value(), nil
+	case reflect.Bool:
+		return v.toBool(buf, t.String())
+	case reflect.String:
+		return v.toString(buf, t.String())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v.toBytes(buf, t.String())
+		}
+	case reflect.Uint8, reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32, reflect.Int, reflect.Uint, reflect.Int64, reflect.Uint64:
+		if v.isInt() {
+			return getIntByKind(buf, v.i, t.Kind(), TypeName(v.tp))
+		}
+		i, err := v.toInt(buf, t.String(), math.MinInt64, math.MaxInt64)
+		if err != nil {
+			return nil, err
+		}
+		return getIntByKind(buf, i, t.Kind(), TypeName(v.tp))
+	case reflect.Float32:
+		f, err := v.toFloat(buf, t.String(), 32)
+		return float32(f), err
+	case reflect.Float64:
+		return v.toFloat(buf, t.String(), 64)
+	}
+	return nil, v.mismatch(buf, t.String())
+}
+
+// adaptScalar adapt the scalar value to v. v can be nil, a pointer, a pointer of pointer or a reflect.Type
+func adaptScalar(buf *Buffer, sv scalar, v interface{}) (interface{}, error) {
+	if v == nil {
+		return sv.value(), nil
+	}
+	if rt, isType := v.(reflect.Type); isType {
+		return sv.coerce(buf, rt)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, sv.mismatch(buf, rv.Type().String())
+	}
+	ev := rv.Elem()
+	if ev.Kind() == reflect.Ptr { // optional value, such as **int32
+		if ev.IsNil() {
+			ev.Set(reflect.New(ev.Type().Elem()))
+		}
+		ev = ev.Elem()
+	}
+	result, err := sv.coerce(buf, ev.Type())
+	if err != nil {
+		return nil, err
+	}
+	if ev.Kind() == reflect.Interface {
+		ev.Set(reflect.ValueOf(result))
+	} else {
+		ev.Set(reflect.ValueOf(result).Convert(ev.Type()))
+	}
+	return result, nil
+}
+
+// getIntByKind convert i to the integer of kind k, ErrOverflow is returned if i is out of the range of k
+func getIntByKind(buf *Buffer, i int64, k reflect.Kind, actual string) (interface{}, error) {
+	var err error
+	switch k {
+	case reflect.Uint8:
+		if err = checkIntRange(buf, i, 0, math.MaxUint8, k.String(), actual); err == nil {
+			return byte(i), nil
+		}
+	case reflect.Int16:
+		if err = checkIntRange(buf, i, math.MinInt16, math.MaxInt16, k.String(), actual); err == nil {
+			return int16(i), nil
+		}
+	case reflect.Uint16:
+		if err = checkIntRange(buf, i, 0, math.MaxUint16, k.String(), actual); err == nil {
+			return uint16(i), nil
+		}
+	case reflect.Int32:
+		if err = checkIntRange(buf, i, math.MinInt32, math.MaxInt32, k.String(), actual); err == nil {
+			return int32(i), nil
+		}
+	case reflect.Uint32:
+		if err = checkIntRange(buf, i, 0, math.MaxUint32, k.String(), actual); err == nil {
+			return uint32(i), nil
+		}
+	case reflect.Int: // for compatible with other language, int is regarded as int32 in breeze. u should use int64 if value over int32
+		if err = checkIntRange(buf, i, math.MinInt32, math.MaxInt32, k.String(), actual); err == nil {
+			return int(i), nil
+		}
+	case reflect.Uint:
+		if err = checkIntRange(buf, i, 0, math.MaxInt64, k.String(), actual); err == nil {
+			return uint(i), nil
+		}
+	case reflect.Int64:
+		return i, nil
+	case reflect.Uint64:
+		if err = checkIntRange(buf, i, 0, math.MaxInt64, k.String(), actual); err == nil {
+			return uint64(i), nil
+		}
+	default:
+		return nil, newTypeMismatchError(buf, k.String(), "Int")
+	}
+	return nil, err
+}
+
+// checkIntRange check whether i which breeze type is actual is in the range [min, max] of the expected type
+func checkIntRange(buf *Buffer, i int64, min int64, max int64, expected string, actual string) error {
+	if i < min || i > max {
+		return newOverflowError(buf, expected, actual, i)
+	}
+	return nil
+}
+
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
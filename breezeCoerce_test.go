@@ -0,0 +1,229 @@
+package breeze
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// expected errors in coercion table
+type coercionError int
+
+const (
+	mismatch coercionError = iota
+	overflow
+)
+
+func TestCoercionTable(t *testing.T) {
+	stringBytes := func(tp byte, s string) []byte { // write string with the type, short strings are direct strings by WriteString
+		if tp == StringType {
+			return append([]byte{StringType, byte(len(s))}, s...)
+		}
+		return append([]byte{byte(len(s))}, s...)
+	}
+	write := func(v interface{}) []byte {
+		buf := NewBuffer(16)
+		WriteValue(buf, v)
+		return buf.Bytes()
+	}
+	sources := []struct {
+		name string
+		data []byte
+	}{
+		{"DirectString", stringBytes(0, "12")},
+		{"String", stringBytes(StringType, "true")},
+		{"String(float)", stringBytes(StringType, "1e40")},
+		{"DirectInt32", write(int32(12))},
+		{"Int32", write(int32(1000))},
+		{"DirectInt64", write(int64(-5))},
+		{"Int64", write(int64(1 << 40))},
+		{"Int64(inexact)", write(int64(1<<53 + 1))},
+		{"Int16", write(int16(-7))},
+		{"Byte", write(byte(200))},
+		{"True", write(true)},
+		{"False", write(false)},
+		{"Float32", write(float32(1.5))},
+		{"Float64", write(1e300)},
+		{"Bytes", write([]byte("ab"))},
+		{"Map", write(map[string]string{})},
+	}
+	targets := []struct {
+		rt   reflect.Type
+		read func(buf *Buffer) (interface{}, error)
+	}{
+		{reflect.TypeOf(false), func(buf *Buffer) (interface{}, error) { var v bool; err := ReadBool(buf, &v); return v, err }},
+		{reflect.TypeOf(""), func(buf *Buffer) (interface{}, error) { var v string; err := ReadString(buf, &v); return v, err }},
+		{reflect.TypeOf([]byte{}), func(buf *Buffer) (interface{}, error) { var v []byte; err := ReadBytes(buf, &v); return v, err }},
+		{reflect.TypeOf(byte(0)), func(buf *Buffer) (interface{}, error) { var v byte; err := ReadByte(buf, &v); return v, err }},
+		{reflect.TypeOf(int16(0)), func(buf *Buffer) (interface{}, error) { var v int16; err := ReadInt16(buf, &v); return v, err }},
+		{reflect.TypeOf(int32(0)), func(buf *Buffer) (interface{}, error) { var v int32; err := ReadInt32(buf, &v); return v, err }},
+		{reflect.TypeOf(int64(0)), func(buf *Buffer) (interface{}, error) { var v int64; err := ReadInt64(buf, &v); return v, err }},
+		{reflect.TypeOf(float32(0)), func(buf *Buffer) (interface{}, error) { var v float32; err := ReadFloat32(buf, &v); return v, err }},
+		{reflect.TypeOf(float64(0)), func(buf *Buffer) (interface{}, error) { var v float64; err := ReadFloat64(buf, &v); return v, err }},
+	}
+	// the expected values of targets: bool, string, []byte, byte, int16, int32, int64, float32, float64
+	expects := map[string][]interface{}{
+		"DirectString":   {mismatch, "12", []byte("12"), byte(12), int16(12), int32(12), int64(12), float32(12), float64(12)},
+		"String":         {true, "true", []byte("true"), mismatch, mismatch, mismatch, mismatch, mismatch, mismatch},
+		"String(float)":  {mismatch, "1e40", []byte("1e40"), mismatch, mismatch, mismatch, mismatch, overflow, 1e40},
+		"DirectInt32":    {mismatch, "12", mismatch, byte(12), int16(12), int32(12), int64(12), float32(12), float64(12)},
+		"Int32":          {mismatch, "1000", mismatch, overflow, int16(1000), int32(1000), int64(1000), float32(1000), float64(1000)},
+		"DirectInt64":    {mismatch, "-5", mismatch, overflow, int16(-5), int32(-5), int64(-5), float32(-5), float64(-5)},
+		"Int64":          {mismatch, "1099511627776", mismatch, overflow, overflow, overflow, int64(1 << 40), float32(1 << 40), float64(1 << 40)},
+		"Int64(inexact)": {mismatch, "9007199254740993", mismatch, overflow, overflow, overflow, int64(1<<53 + 1), overflow, overflow},
+		"Int16":          {mismatch, "-7", mismatch, overflow, int16(-7), int32(-7), int64(-7), float32(-7), float64(-7)},
+		"Byte":           {mismatch, "200", mismatch, byte(200), int16(200), int32(200), int64(200), float32(200), float64(200)},
+		"True":           {true, "true", mismatch, mismatch, mismatch, mismatch, mismatch, mismatch, mismatch},
+		"False":          {false, "false", mismatch, mismatch, mismatch, mismatch, mismatch, mismatch, mismatch},
+		"Float32":        {mismatch, "1.5", mismatch, mismatch, mismatch, mismatch, mismatch, float32(1.5), float64(1.5)},
+		"Float64":        {mismatch, strconv.FormatFloat(1e300, 'f', -1, 64), mismatch, mismatch, mismatch, mismatch, mismatch, overflow, 1e300},
+		"Bytes":          {mismatch, mismatch, []byte("ab"), mismatch, mismatch, mismatch, mismatch, mismatch, mismatch},
+		"Map":            {mismatch, mismatch, mismatch, mismatch, mismatch, mismatch, mismatch, mismatch, mismatch},
+	}
+	check := func(source string, target reflect.Type, by string, expect interface{}, v interface{}, err error) {
+		var mismatchErr *TypeMismatchError
+		switch expect {
+		case mismatch:
+			if !errors.As(err, &mismatchErr) {
+				t.Errorf("%s to %s by %s should be type mismatch. value:%v, err:%v", source, target, by, v, err)
+			}
+		case overflow:
+			if !errors.Is(err, ErrOverflow) {
+				t.Errorf("%s to %s by %s should be overflow. value:%v, err:%v", source, target, by, v, err)
+			}
+		default:
+			if err != nil || !reflect.DeepEqual(v, expect) {
+				t.Errorf("wrong coercion of %s to %s by %s. expect:%v, real:%v, err:%v", source, target, by, expect, v, err)
+			}
+		}
+	}
+	for _, source := range sources {
+		for i, target := range targets {
+			expect := expects[source.name][i]
+			v, err := target.read(CreateBuffer(source.data))
+			check(source.name, target.rt, "Read function", expect, v, err)
+			v, err = ReadValue(CreateBuffer(source.data), target.rt)
+			check(source.name, target.rt, "ReadValue", expect, v, err)
+			// read into pointer
+			ptr := reflect.New(target.rt)
+			_, err = ReadValue(CreateBuffer(source.data), ptr.Interface())
+			check(source.name, target.rt, "pointer", expect, ptr.Elem().Interface(), err)
+		}
+	}
+
+	// unsigned integers and interface
+	if v, err := ReadValue(CreateBuffer(stringBytes(0, "65535")), reflect.TypeOf(uint16(0))); err != nil || v != uint16(math.MaxUint16) {
+		t.Errorf("wrong uint16 from string. err:%v, value:%v", err, v)
+	}
+	if v, err := ReadValue(CreateBuffer(write(int16(-1))), reflect.TypeOf(uint(0))); !errors.Is(err, ErrOverflow) {
+		t.Errorf("negative int16 should overflow uint. err:%v, value:%v", err, v)
+	}
+	if v, err := ReadValue(CreateBuffer(write(int32(1))), reflect.TypeOf((*interface{})(nil)).Elem()); err != nil || v != 1 {
+		t.Errorf("int32 should be read as int into interface. err:%v, value:%v", err, v)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	write := func(v interface{}) []byte {
+		buf := NewBuffer(16)
+		WriteValue(buf, v)
+		return buf.Bytes()
+	}
+	intString := append([]byte{StringType, 3}, "123"...)
+	tests := []struct {
+		data   []byte
+		target interface{}
+		expect interface{} // nil means type mismatch
+	}{
+		{intString, int64(0), nil},
+		{intString, "", "123"},
+		{intString, []byte{}, nil},
+		{append([]byte{StringType, 4}, "true"...), false, nil},
+		{write(true), false, true},
+		{write(true), "", nil},
+		{write(int32(12)), "", nil},
+		{write(int32(12)), int64(0), int64(12)},
+		{write(int64(12)), int16(0), int16(12)},
+		{write(byte(200)), uint(0), uint(200)},
+		{write(int32(12)), float64(0), nil},
+		{write(float32(1.5)), float64(0), 1.5},
+		{write(1.5), float32(0), float32(1.5)},
+		{write(1.5), int64(0), nil},
+		{write([]byte("ab")), "", nil},
+		{write([]byte("ab")), []byte{}, []byte("ab")},
+		{intString, nil, "123"},
+	}
+	for _, test := range tests {
+		buf := CreateBuffer(test.data)
+		buf.SetStrict(true)
+		var v interface{}
+		var err error
+		if test.target == nil {
+			v, err = ReadValue(buf, nil)
+		} else {
+			v, err = ReadValue(buf, reflect.TypeOf(test.target))
+		}
+		var mismatchErr *TypeMismatchError
+		if test.expect == nil {
+			if !errors.As(err, &mismatchErr) {
+				t.Errorf("%v to %T should be type mismatch in strict mode. value:%v, err:%v", test.data, test.target, v, err)
+			}
+		} else if err != nil || !reflect.DeepEqual(v, test.expect) {
+			t.Errorf("wrong value of %v to %T in strict mode. expect:%v, real:%v, err:%v", test.data, test.target, test.expect, v, err)
+		}
+	}
+
+	// message field
+	msg := &GenericMessage{Name: "motan.TestMsg"}
+	msg.PutField(1, "123")
+	buf := NewBuffer(64)
+	WriteValue(buf, msg)
+	data := buf.Bytes()
+	testMsg := &TestMsg{}
+	if _, err := ReadValue(CreateBuffer(data), testMsg); err != nil || testMsg.MyInt != 123 {
+		t.Errorf("string field should be coerced without strict mode. err:%v, value:%d", err, testMsg.MyInt)
+	}
+	buf = CreateBuffer(data)
+	buf.SetStrict(true)
+	var mismatchErr *TypeMismatchError
+	if _, err := ReadValue(buf, &TestMsg{}); !errors.As(err, &mismatchErr) {
+		t.Errorf("string field should be type mismatch in strict mode. err:%v", err)
+	} else if mismatchErr.Path != "TestMsg.myInt" {
+		t.Errorf("wrong path of type mismatch: %s", mismatchErr.Path)
+	}
+
+	// collection type and element type
+	fields := []struct {
+		index int
+		value interface{}
+		path  string
+	}{
+		{10, []string{"a", "b"}, "TestSubMsg.myArray"},
+		{10, int32(5), "TestSubMsg.myArray"},
+		{10, map[int32]int32{1: 2}, "TestSubMsg.myArray"},
+		{9, []int32{1}, "TestSubMsg.myMap2"},
+	}
+	for _, field := range fields {
+		msg := &GenericMessage{Name: "motan.TestSubMsg"}
+		msg.PutField(1, "s")
+		msg.PutField(field.index, field.value)
+		msg.PutField(11, true)
+		buf := NewBuffer(64)
+		buf.SetCanonical(true)
+		WriteValue(buf, msg)
+		buf = CreateBuffer(buf.Bytes())
+		buf.SetStrict(true)
+		if _, err := ReadValue(buf, &TestSubMsg{}); !errors.As(err, &mismatchErr) {
+			t.Errorf("%T of field %d should be type mismatch in strict mode. err:%v", field.value, field.index, err)
+		} else if mismatchErr.Path != field.path {
+			t.Errorf("wrong path of type mismatch: %s", mismatchErr.Path)
+		}
+	}
+	buf = NewBuffer(64)
+	WriteValue(buf, map[string]int32{"a": 1})
+	if _, err := ReadStringStringMap(CreateBuffer(buf.Bytes()), true); !errors.As(err, &mismatchErr) {
+		t.Errorf("map[string]int32 should not be read as map[string]string. err:%v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package breeze
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TypeMismatchError is returned when a breeze value can not be read as the expected type
+type TypeMismatchError struct {
+	Offset   int    // read offset of buffer when the error is found
+	Expected string // expected type, such as int32 or a message name
+	Actual   string // actual breeze type name or message name
+	Path     string // field path of the value, such as TestMsg.myMap["m1"].myInt
+}
+
+func (e *TypeMismatchError) Error() string {
+	return "breeze: type mismatch, expect " + e.Expected + ", actual " + e.Actual + errorPosition(e.Offset, e.Path)
+}
+
+// OverflowError is returned when a breeze value is out of the range of the expected type,
+// or a go value can not be written as breeze value, such as uint64 over math.MaxInt64
+type OverflowError struct {
+	Offset   int // read offset, or write offset if the value can not be written
+	Expected string
+	Actual   string
+	Value    string // the overflowed value
+	Path     string
+}
+
+func (e *OverflowError) Error() string {
+	return "breeze: value " + e.Value + " of " + e.Actual + " overflows " + e.Expected + errorPosition(e.Offset, e.Path)
+}
+
+// Unwrap makes OverflowError match ErrOverflow by errors.Is
+func (e *OverflowError) Unwrap() error {
+	return ErrOverflow
+}
+
+// UnknownTypeError is returned when a type byte is not a known breeze type
+type UnknownTypeError struct {
+	Offset   int
+	Type     byte
+	Expected string
+	Actual   string
+	Path     string
+}
+
+func (e *UnknownTypeError) Error() string {
+	s := "breeze: unknown type " + e.Actual
+	if e.Expected != "" {
+		s += ", expect " + e.Expected
+	}
+	return s + errorPosition(e.Offset, e.Path)
+}
+
+func errorPosition(offset int, path string) string {
+	s := ", offset " + strconv.Itoa(offset)
+	if path != "" {
+		s += ", path " + path
+	}
+	return s
+}
+
+func newTypeMismatchError(buf *Buffer, expected string, actual string) error {
+	return &TypeMismatchError{Offset: buf.GetRPos(), Expected: expected, Actual: actual}
+}
+
+func newOverflowError(buf *Buffer, expected string, actual string, value interface{}) error {
+	return &OverflowError{Offset: buf.GetRPos(), Expected: expected, Actual: actual, Value: fmt.Sprint(value)}
+}
+
+func newUnknownTypeError(buf *Buffer, expected string, tp byte) error {
+	return &UnknownTypeError{Offset: buf.GetRPos(), Type: tp, Expected: expected, Actual: TypeName(tp)}
+}
+
+// withPath add a path segment before the path of error
+func withPath(err error, segment string) error {
+	switch e := err.(type) {
+	case *TypeMismatchError:
+		e.Path = segment + e.Path
+	case *OverflowError:
+		e.Path = segment + e.Path
+	case *UnknownTypeError:
+		e.Path = segment + e.Path
+	}
+	return err
+}
+
+// setReadingMessage set the message which fields will be read by ReadMessageField next
+func setReadingMessage(buf *Buffer, name string, schema *Schema) {
+	buf.readingName = name
+	buf.readingSchema = schema
+}
+
+// hasPath check whether the error can carry a field path
+func hasPath(err error) bool {
+	switch err.(type) {
+	case *TypeMismatchError, *OverflowError, *UnknownTypeError:
+		return true
+	}
+	return false
+}
+
+// keySegment get the path segment of a map key, such as ["m1"]
+func keySegment(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return "[" + strconv.Quote(s) + "]"
+	}
+	return "[" + fmt.Sprint(key) + "]"
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// fieldSegment get the path segment of a message field, the field name is used if the schema of message is found
+func fieldSegment(buf *Buffer, name string, schema *Schema, index int) string {
+	if schema == nil && name != "" {
+		if schema = buf.GetContext().GetSchema(name); schema == nil {
+			schema = getRegisteredSchema(name)
+		}
+	}
+	if schema != nil {
+		if field := schema.GetFieldByIndex(index); field != nil {
+			return "." + field.Name
+		}
+	}
+	return "." + strconv.Itoa(index)
+}
+
+// TypeName get the name of a breeze type byte, such as DirectInt32, PackedMap and RefMessage#3
+func TypeName(tp byte) string {
+	switch {
+	case tp <= DirectStringMaxType:
+		return "DirectString"
+	case tp == StringType:
+		return "String"
+	case tp >= DirectInt32MinType && tp <= DirectInt32MaxType:
+		return "DirectInt32"
+	case tp == Int32Type:
+		return "Int32"
+	case tp >= DirectInt64MinType && tp <= DirectInt64MaxType:
+		return "DirectInt64"
+	case tp > RefMessageType:
+		return "RefMessage#" + strconv.Itoa(int(tp-RefMessageType))
+	}
+	switch tp {
+	case Int64Type:
+		return "Int64"
+	case NullType:
+		return "Null"
+	case TrueType:
+		return "True"
+	case FalseType:
+		return "False"
+	case ByteType:
+		return "Byte"
+	case BytesType:
+		return "Bytes"
+	case Int16Type:
+		return "Int16"
+	case Float32Type:
+		return "Float32"
+	case Float64Type:
+		return "Float64"
+	case MapType:
+		return "Map"
+	case ArrayType:
+		return "Array"
+	case PackedMapType:
+		return "PackedMap"
+	case PackedArrayType:
+		return "PackedArray"
+	case SchemaType:
+		return "Schema"
+	case MessageType:
+		return "Message"
+	case RefMessageType:
+		return "RefMessage"
+	}
+	return "Unknown(0x" + strconv.FormatInt(int64(tp), 16) + ")"
+}
@@ -0,0 +1,141 @@
+package breeze
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type testPathMsg struct {
+	_ struct{}          `breeze:"name=motan.Unknown"`
+	M map[string][]bool `breeze:"3"`
+}
+
+func TestReadErrorPath(t *testing.T) {
+	// the field names of packed messages are got from registered schema
+	RegisterMessage(&TestSubMsg{})
+	defer func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		delete(messageTypes, testSubMsgBreezeSchema.Name)
+	}()
+	sub := &GenericMessage{Name: "motan.TestSubMsg"}
+	sub.PutField(1, "s")
+	sub.PutField(2, true) // bool for myInt
+	msg := &GenericMessage{Name: "motan.TestMsg"}
+	msg.PutField(1, int32(1))
+	msg.PutField(3, map[string]*GenericMessage{"m1": sub})
+	buf := NewBuffer(128)
+	WriteValue(buf, msg)
+	data := buf.Bytes()
+
+	// generated message
+	_, err := ReadValue(CreateBuffer(data), &TestMsg{})
+	var mismatch *TypeMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("should be TypeMismatchError. err:%v", err)
+	}
+	if mismatch.Path != `TestMsg.myMap["m1"].myInt` || mismatch.Expected != "int32" || mismatch.Actual != "True" || mismatch.Offset <= 0 || mismatch.Offset > len(data) {
+		t.Errorf("wrong TypeMismatchError: %+v", mismatch)
+	}
+
+	// tagged struct
+	_, err = ReadValue(CreateBuffer(data), &testStructMsg{})
+	if !errors.As(err, &mismatch) || mismatch.Path != `TestMsg.myMap["m1"].myInt` {
+		t.Errorf("wrong TypeMismatchError of struct. err:%v", err)
+	}
+
+	// map of messages, field index is used without schema
+	unknown := &GenericMessage{Name: "motan.Unknown"}
+	unknown.PutField(3, map[string]interface{}{"k": []interface{}{true, "a"}})
+	buf.Reset()
+	WriteValue(buf, map[string]*GenericMessage{"u": unknown})
+	_, err = ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(map[string]map[int]map[string][]string{}))
+	if !errors.As(err, &mismatch) || mismatch.Path != `["u"]` {
+		t.Errorf("wrong TypeMismatchError of map. err:%v", err)
+	}
+	_, err = ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(map[string]*testPathMsg{}))
+	if !errors.As(err, &mismatch) || mismatch.Path != `["u"].m["k"][1]` {
+		t.Errorf("wrong TypeMismatchError of nested collections. err:%v", err)
+	}
+	data = []byte{ArrayType, 2, 0x01, 'a', 0xa5}
+	_, err = ReadValue(CreateBuffer(data), reflect.TypeOf([]string{}))
+	var unknownType *UnknownTypeError
+	if !errors.As(err, &unknownType) || unknownType.Type != 0xa5 || unknownType.Path != "[1]" || unknownType.Actual != "Unknown(0xa5)" {
+		t.Errorf("wrong UnknownTypeError. err:%v", err)
+	}
+
+	overflow := error(&OverflowError{Expected: "int16", Actual: "Int32", Value: "40000"})
+	if !errors.Is(overflow, ErrOverflow) {
+		t.Errorf("OverflowError should be ErrOverflow")
+	}
+}
+
+func TestReadOverflow(t *testing.T) {
+	write := func(v interface{}) *Buffer {
+		buf := NewBuffer(32)
+		WriteValue(buf, v)
+		return CreateBuffer(buf.Bytes())
+	}
+	writeString := func(s string) *Buffer { // the short strings are written as direct strings
+		return CreateBuffer(append([]byte{StringType, byte(len(s))}, s...))
+	}
+	var i16 int16
+	var i32 int32
+	var f32 float32
+	tests := []struct {
+		read   func() error
+		errMsg string
+	}{
+		{func() error { return ReadInt32(write(int64(3000000000)), &i32) }, "breeze: value 3000000000 of Int64 overflows int32, offset 6"},
+		{func() error { return ReadInt32(writeString("-3000000000"), &i32) }, "breeze: value -3000000000 of String overflows int32, offset 13"},
+		{func() error { return ReadInt16(write(int32(40000)), &i16) }, "breeze: value 40000 of Int32 overflows int16, offset 4"},
+		{func() error { return ReadInt16(write(int64(-40000)), &i16) }, "breeze: value -40000 of Int64 overflows int16, offset 4"},
+		{func() error { return ReadInt16(writeString("99999999999999999999"), &i16) }, "breeze: value 99999999999999999999 of String overflows int16, offset 22"},
+		{func() error { return ReadFloat32(write(1e300), &f32) }, "breeze: value 1e+300 of Float64 overflows float32, offset 9"},
+		{func() error { _, err := ReadValue(write(int32(-1)), reflect.TypeOf(uint32(0))); return err }, "breeze: value -1 of Int32 overflows uint32, offset 1"},
+		{func() error { _, err := ReadValue(write(int64(-1)), reflect.TypeOf(uint64(0))); return err }, "breeze: value -1 of Int64 overflows uint64, offset 1"},
+		{func() error { _, err := ReadValue(write(int64(1<<40)), reflect.TypeOf(0)); return err }, "breeze: value 1099511627776 of Int64 overflows int, offset 7"},
+		{func() error { _, err := ReadValue(write(int32(70000)), reflect.TypeOf(uint16(0))); return err }, "breeze: value 70000 of Int32 overflows uint16, offset 4"},
+		{func() error { _, err := ReadValue(write("1e40"), reflect.TypeOf(float32(0))); return err }, "breeze: value 1e40 of String overflows float32, offset 5"},
+		{func() error { _, err := ReadValue(write([]int64{1, 1 << 20}), reflect.TypeOf([]int16{})); return err }, "breeze: value 1048576 of Int64 overflows int16, offset 8, path [1]"},
+	}
+	for i, test := range tests {
+		err := test.read()
+		if !errors.Is(err, ErrOverflow) || err.Error() != test.errMsg {
+			t.Errorf("wrong overflow error of test %d. expect:%s, real:%v", i, test.errMsg, err)
+		}
+	}
+
+	// field path of message
+	msg := &GenericMessage{Name: testMsgBreezeSchema.Name}
+	msg.PutField(1, int64(1<<33))
+	_, err := ReadValue(write(msg), &TestMsg{})
+	var overflow *OverflowError
+	if !errors.As(err, &overflow) || overflow.Path != "TestMsg.myInt" || overflow.Expected != "int32" || overflow.Actual != "Int64" {
+		t.Errorf("wrong OverflowError of message field. err:%v", err)
+	}
+
+	// values in range
+	if err = ReadInt16(write(int64(-32768)), &i16); err != nil || i16 != -32768 {
+		t.Errorf("wrong int16. err:%v, value:%d", err, i16)
+	}
+	if err = ReadFloat32(write(1.5), &f32); err != nil || f32 != 1.5 {
+		t.Errorf("wrong float32. err:%v, value:%v", err, f32)
+	}
+	if v, err := ReadValue(write(int64(math.MaxUint32)), reflect.TypeOf(uint32(0))); err != nil || v != uint32(math.MaxUint32) {
+		t.Errorf("wrong uint32. err:%v, value:%v", err, v)
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	names := map[byte]string{0x00: "DirectString", StringType: "String", 0x50: "DirectInt32", Int32Type: "Int32",
+		0x88: "DirectInt64", Int64Type: "Int64", PackedMapType: "PackedMap", RefMessageType: "RefMessage",
+		0xe3: "RefMessage#4", 0xa1: "Unknown(0xa1)"}
+	for tp, name := range names {
+		if TypeName(tp) != name {
+			t.Errorf("wrong type name of %x. expect:%s, real:%s", tp, name, TypeName(tp))
+		}
+	}
+}
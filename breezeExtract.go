@@ -0,0 +1,315 @@
+package breeze
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Extract read a breeze value with type from the buffer, and get the values at paths without decoding the other values.
+
+A path is the same as the field path in errors, such as:
+
+	subMsg.myInt       // field of message by field name
+	5.2                // field of message by field index
+	myMap["m1"].myInt  // value of map by key, the quotes can be omitted: myMap[m1]
+	myArray[0]         // element of array by index
+	myMap[*].myString  // all values of map or elements of array
+
+The field names are found by the schemas in the Context of buffer and the registered messages and enums.
+The values of a path with [*] are returned as []interface{} in the encoded order. The paths which are not found in the value
+are not in the returned map. The values are read as ReadValue(buf, nil) does, and the others are skipped as SkipValue does.
+*/
+func Extract(buf *Buffer, paths ...string) (map[string]interface{}, error) {
+	matches := make([]extractMatch, 0, len(paths))
+	for i, p := range paths {
+		if indexOf(paths[:i], p) >= 0 { // duplicated path
+			continue
+		}
+		steps, err := parseExtractPath(p)
+		if err != nil {
+			return nil, err
+		}
+		target := &extractTarget{path: p, steps: steps}
+		for _, step := range steps {
+			target.multiple = target.multiple || step.isAll()
+		}
+		matches = append(matches, extractMatch{target: target})
+	}
+	tp, name, err := readType(buf)
+	if err != nil {
+		return nil, err
+	}
+	e := &extractor{result: make(map[string]interface{}, len(matches))}
+	if err = e.extract(buf, tp, name, matches); err != nil {
+		return nil, err
+	}
+	return e.result, nil
+}
+
+// extractStep is a step of extract path, a message field or a selector of map or array
+type extractStep struct {
+	isField bool
+	name    string // field name or index if isField, otherwise map key or array index. * means all
+	quoted  bool   // the map key is a quoted string
+}
+
+func (s *extractStep) isAll() bool {
+	return !s.isField && !s.quoted && s.name == "*"
+}
+
+func (s *extractStep) matchField(index int, schema *Schema) bool {
+	if i, err := strconv.Atoi(s.name); err == nil {
+		return i == index
+	}
+	if schema != nil {
+		if field := schema.GetFieldByName(s.name); field != nil {
+			return field.Index == index
+		}
+	}
+	return false
+}
+
+func (s *extractStep) matchKey(key interface{}) bool {
+	if s.isAll() {
+		return true
+	}
+	if k, ok := key.(string); ok {
+		return k == s.name
+	}
+	return !s.quoted && keySegment(key) == "["+s.name+"]"
+}
+
+func (s *extractStep) matchIndex(index int) bool {
+	if s.isAll() {
+		return true
+	}
+	i, err := strconv.Atoi(s.name)
+	return !s.quoted && err == nil && i == index
+}
+
+// parseExtractPath parse a path into steps, an empty path means the value itself
+func parseExtractPath(path string) ([]extractStep, error) {
+	var steps []extractStep
+	invalid := func(reason string) error {
+		return errors.New("breeze: invalid extract path " + strconv.Quote(path) + ", " + reason)
+	}
+	for s := path; s != ""; {
+		if s[0] != '[' {
+			if len(steps) > 0 {
+				if s[0] != '.' {
+					return nil, invalid("unexpected " + strconv.Quote(s[:1]))
+				}
+				s = s[1:]
+			}
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, invalid("empty field")
+			}
+			steps = append(steps, extractStep{isField: true, name: s[:end]})
+			s = s[end:]
+			continue
+		}
+		if len(s) > 1 && s[1] == '"' {
+			end := 2
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s)-1 || s[end+1] != ']' {
+				return nil, invalid("unclosed key")
+			}
+			key, err := strconv.Unquote(s[1 : end+1])
+			if err != nil {
+				return nil, invalid("wrong key " + s[1:end+1])
+			}
+			steps = append(steps, extractStep{name: key, quoted: true})
+			s = s[end+2:]
+			continue
+		}
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return nil, invalid("unclosed key")
+		}
+		steps = append(steps, extractStep{name: s[1:end]})
+		s = s[end+1:]
+	}
+	return steps, nil
+}
+
+type extractTarget struct {
+	path     string
+	steps    []extractStep
+	multiple bool // the path has [*]
+}
+
+// extractMatch is a target which steps before step are matched
+type extractMatch struct {
+	target *extractTarget
+	step   int
+}
+
+type extractor struct {
+	result map[string]interface{}
+}
+
+// extract walk a value which breeze type is tp(and message name is name), the type has been read
+func (e *extractor) extract(buf *Buffer, tp byte, name string, matches []extractMatch) error {
+	if len(matches) == 0 {
+		return skipValueByType(buf, tp)
+	}
+	var next []extractMatch
+	found := false
+	for _, m := range matches {
+		if m.step == len(m.target.steps) {
+			found = true
+		} else {
+			next = append(next, m)
+		}
+	}
+	if found {
+		pos, count := buf.GetRPos(), buf.GetContext().messageTypeRefCount
+		v, err := readValueByType(buf, nil, false, tp, name)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			if m.step == len(m.target.steps) {
+				e.put(m.target, v)
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		// walk the value again for the longer paths
+		buf.SetRPos(pos)
+		buf.GetContext().truncateMessageType(count)
+	}
+	switch tp {
+	case MessageType:
+		return e.extractMessage(buf, name, next)
+	case MapType, PackedMapType, ArrayType, PackedArrayType:
+		return e.extractCollection(buf, tp, next)
+	}
+	return skipValueByType(buf, tp)
+}
+
+func (e *extractor) extractMessage(buf *Buffer, name string, matches []extractMatch) error {
+	schema := buf.GetContext().GetSchema(name)
+	if schema == nil {
+		schema = getRegisteredSchema(name)
+	}
+	setReadingMessage(buf, name, schema)
+	return ReadMessageField(buf, func(buf *Buffer, index int) error {
+		var next []extractMatch
+		for _, m := range matches {
+			if step := m.target.steps[m.step]; step.isField && step.matchField(index, schema) {
+				next = append(next, extractMatch{target: m.target, step: m.step + 1})
+			}
+		}
+		tp, name, err := readType(buf)
+		if err != nil {
+			return err
+		}
+		return e.extract(buf, tp, name, next)
+	})
+}
+
+func (e *extractor) extractCollection(buf *Buffer, tp byte, matches []extractMatch) (err error) {
+	total, err := buf.ReadVarInt()
+	if err != nil || total == 0 {
+		return err
+	}
+	if total > uint64(buf.Remain()) { // every element takes one byte at least
+		return ErrNotEnough
+	}
+	if err = enterRead(buf); err != nil {
+		return err
+	}
+	defer leaveRead(buf)
+	isMap := tp == MapType || tp == PackedMapType
+	isPacked := tp == PackedMapType || tp == PackedArrayType
+	var ktp, vtp byte
+	var kn, vn string
+	if isPacked {
+		if ktp, kn, err = readType(buf); err != nil { // key type of map or element type of array
+			return err
+		}
+		vtp, vn = ktp, kn
+		if isMap {
+			if vtp, vn, err = readType(buf); err != nil { // value type
+				return err
+			}
+		}
+	}
+	// readElemType read the type of next element, the type of packed element is the type in header
+	readElemType := func(ptp byte, pname string) (byte, string, error) {
+		if !isPacked {
+			return readType(buf)
+		}
+		tp, err := packedElemType(buf, ptp)
+		return tp, pname, err
+	}
+	needKey := false
+	for _, m := range matches {
+		step := m.target.steps[m.step]
+		needKey = needKey || (isMap && !step.isField && !step.isAll())
+	}
+	for i := 0; i < int(total); i++ {
+		var key interface{}
+		if isMap {
+			tp, name, err := readElemType(ktp, kn)
+			if err != nil {
+				return err
+			}
+			if needKey {
+				key, err = readValueByType(buf, nil, false, tp, name)
+			} else {
+				err = skipValueByType(buf, tp)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		var next []extractMatch
+		for _, m := range matches {
+			step := m.target.steps[m.step]
+			if !step.isField && (isMap && step.matchKey(key) || !isMap && step.matchIndex(i)) {
+				next = append(next, extractMatch{target: m.target, step: m.step + 1})
+			}
+		}
+		tp, name, err := readElemType(vtp, vn)
+		if err != nil {
+			return err
+		}
+		if err = e.extract(buf, tp, name, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) put(target *extractTarget, v interface{}) {
+	if !target.multiple {
+		e.result[target.path] = v
+		return
+	}
+	values, _ := e.result[target.path].([]interface{})
+	e.result[target.path] = append(values, v)
+}
+
+func indexOf(s []string, v string) int {
+	for i, e := range s {
+		if e == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package breeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	msg := getTestMsg()
+	msg.SubMsg = getTestSubMsgByInt(-7)
+	buf := NewBuffer(1024)
+	WriteValue(buf, msg)
+	data := buf.Bytes()
+	newBuffer := func() *Buffer {
+		buf := CreateBuffer(data)
+		buf.GetContext().PutSchema(testMsgBreezeSchema)
+		buf.GetContext().PutSchema(testSubMsgBreezeSchema)
+		return buf
+	}
+
+	paths := []string{"myInt", "2", "subMsg.myInt", "5.2", "myMap[*].myString", `myMap["m1"].myInt64`, "myMap[m1].myBool",
+		"myArray[0].myMap2[12]", "myArray[*].myMap1[jdie]", "myArray[1].myInt", "myMap[m2]", "myInt.myString", "myMap.myString"}
+	rbuf := newBuffer()
+	result, err := Extract(rbuf, paths...)
+	if err != nil {
+		t.Fatalf("extract fail. err:%v", err)
+	}
+	if rbuf.Remain() != 0 {
+		t.Errorf("the value should be read completely. remain:%d", rbuf.Remain())
+	}
+	expects := map[string]interface{}{
+		"myInt":                   int32(12),
+		"2":                       "jiernoce",
+		"subMsg.myInt":            int32(-7),
+		"5.2":                     int32(-7),
+		"myMap[*].myString":       []interface{}{"uoiwer"},
+		`myMap["m1"].myInt64`:     int64(234),
+		"myMap[m1].myBool":        true,
+		"myArray[0].myMap2[12]":   []interface{}{34, -15}, // int32 elements are read as int in interface arrays
+		"myArray[*].myMap1[jdie]": []interface{}{[]byte("ierjkkkd")},
+	}
+	if !reflect.DeepEqual(result, expects) {
+		t.Errorf("wrong extract result. expect:%#v, real:%#v", expects, result)
+	}
+
+	// a path and its sub path
+	result, err = Extract(newBuffer(), "subMsg", "subMsg.myString", "myArray[*]", "myArray[*].myInt")
+	if err != nil {
+		t.Fatalf("extract fail. err:%v", err)
+	}
+	sub, ok := result["subMsg"].(*GenericMessage)
+	if !ok || sub.GetFieldByIndex(2) != int32(-7) || result["subMsg.myString"] != "uoiwer" {
+		t.Errorf("wrong extract result of sub path: %v", result)
+	}
+	if array, ok := result["myArray[*]"].([]interface{}); !ok || len(array) != 1 ||
+		!reflect.DeepEqual(result["myArray[*].myInt"], []interface{}{int32(2134)}) {
+		t.Errorf("wrong extract result of sub path: %v", result)
+	}
+
+	// field names without schema
+	result, err = Extract(CreateBuffer(data), "myInt", "1")
+	if err != nil || !reflect.DeepEqual(result, map[string]interface{}{"1": int32(12)}) {
+		t.Errorf("field name should not be found without schema. err:%v, result:%v", err, result)
+	}
+
+	for _, path := range []string{`myMap["m1`, "myMap[m1", "myMap..myInt", "myMap[m1]myInt", ".myInt"} {
+		if _, err = Extract(newBuffer(), path); err == nil {
+			t.Errorf("path %s should be invalid", path)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package breeze
+
+import (
+	"github.com/pkg/errors"
+)
+
+/*
+A breeze frame carries one or more breeze values on a stream connection such as raw TCP.
+The layout of a frame is:
+
+	magic(2 bytes, 0x425a) | version(1 byte) | flags(1 byte) | payload length(uint32) | payload(breeze values)
+
+all frames written into or read from a Buffer share the message type refs of its Context,
+unless the frame has flag FrameFlagResetContext, which resets the message type refs before the payload.
+*/
+
+// frame constants
+const (
+	FrameMagic      uint16 = 0x425a // "BZ"
+	FrameVersion    byte   = 1
+	FrameHeaderSize        = 8
+)
+
+// frame flags
+const (
+	FrameFlagResetContext byte = 1 << iota // reset message type refs of Context before the frame payload
+)
+
+// frame errors
+var (
+	ErrWrongFrameMagic   = errors.New("breeze: wrong frame magic")
+	ErrWrongFrameVersion = errors.New("breeze: unsupported frame version")
+)
+
+// Frame is a frame read from Buffer
+type Frame struct {
+	Version byte
+	Flags   byte
+	// Payload holds the values of the frame. it shares bytes and Context with the Buffer where the frame is read from
+	Payload *Buffer
+}
+
+// WriteFrame write values into buf as a frame.
+// if an error occurs, the bytes and the message type refs written by the frame will be rolled back.
+func WriteFrame(buf *Buffer, flags byte, values ...interface{}) error {
+	ctx := buf.GetContext()
+	if flags&FrameFlagResetContext == FrameFlagResetContext {
+		ctx.truncateMessageType(0)
+	}
+	start := buf.GetWPos()
+	count := ctx.messageTypeRefCount
+	buf.WriteUint16(FrameMagic)
+	buf.WriteByte(FrameVersion)
+	buf.WriteByte(flags)
+	pos := skipLength(buf)
+	for _, v := range values {
+		if err := WriteValue(buf, v); err != nil {
+			buf.SetWPos(start)
+			ctx.truncateMessageType(count)
+			return err
+		}
+	}
+	writeLength(buf, pos)
+	return nil
+}
+
+// ReadFrame read a frame from buf. values of the frame can be read from Frame.Payload by ReadValue.
+// the payload shares the Context, DecodeLimits and strict mode of buf.
+// if buf does not contain a complete frame, ErrNotEnough will be returned and the read position of buf will not change,
+// so it can be called again when more bytes are received.
+func ReadFrame(buf *Buffer) (*Frame, error) {
+	if buf.Remain() < FrameHeaderSize {
+		return nil, ErrNotEnough
+	}
+	start := buf.GetRPos()
+	magic, _ := buf.ReadUint16()
+	if magic != FrameMagic {
+		buf.SetRPos(start)
+		return nil, ErrWrongFrameMagic
+	}
+	frame := &Frame{}
+	frame.Version, _ = buf.ReadByte()
+	if frame.Version != FrameVersion {
+		buf.SetRPos(start)
+		return nil, ErrWrongFrameVersion
+	}
+	frame.Flags, _ = buf.ReadByte()
+	size, _ := buf.ReadUint32()
+	if uint32(buf.Remain()) < size {
+		buf.SetRPos(start)
+		return nil, ErrNotEnough
+	}
+	data, _ := buf.Next(int(size))
+	if frame.Flags&FrameFlagResetContext == FrameFlagResetContext {
+		buf.GetContext().truncateMessageType(0)
+	}
+	frame.Payload = CreateBuffer(data)
+	frame.Payload.context = buf.GetContext()
+	// the payload is read in the same way as buf
+	frame.Payload.limits = buf.limits
+	frame.Payload.strict = buf.strict
+	return frame, nil
+}
@@ -0,0 +1,104 @@
+package breeze
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// frame fixtures for interop tests
+var (
+	// frame of values: "hello", int32(1), map[string]int32{"a": 2}
+	valuesFrameFixture = []byte{0x42, 0x5a, 0x01, 0x00, 0x00, 0x00, 0x00, 0x0e,
+		0x05, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x51, 0xdb, 0x01, 0x3f, 0x7f, 0x01, 0x61, 0x04}
+	// two frames of message motan.Fx{1: "x"}, the first frame resets context, the second frame uses message type ref
+	messageFramesFixture = []byte{0x42, 0x5a, 0x01, 0x01, 0x00, 0x00, 0x00, 0x11,
+		0xde, 0x08, 0x6d, 0x6f, 0x74, 0x61, 0x6e, 0x2e, 0x46, 0x78, 0x00, 0x00, 0x00, 0x03, 0x01, 0x01, 0x78,
+		0x42, 0x5a, 0x01, 0x00, 0x00, 0x00, 0x00, 0x08,
+		0xe0, 0x00, 0x00, 0x00, 0x03, 0x01, 0x01, 0x78}
+)
+
+func TestWriteFrame(t *testing.T) {
+	buf := NewBuffer(64)
+	err := WriteFrame(buf, 0, "hello", int32(1), map[string]int32{"a": 2})
+	if err != nil || !bytes.Equal(buf.Bytes(), valuesFrameFixture) {
+		t.Errorf("write frame not correct. err:%v, expect:%v, real:%v", err, valuesFrameFixture, buf.Bytes())
+	}
+
+	buf = NewBuffer(64)
+	msg := &GenericMessage{Name: "motan.Fx"}
+	msg.PutField(1, "x")
+	WriteFrame(buf, 0, msg) // frame will be overwritten
+	buf.Reset()
+	WriteFrame(buf, FrameFlagResetContext, msg)
+	WriteFrame(buf, 0, msg)
+	if !bytes.Equal(buf.Bytes(), messageFramesFixture) {
+		t.Errorf("write frame not correct. expect:%v, real:%v", messageFramesFixture, buf.Bytes())
+	}
+
+	// rollback when write fail
+	pos := buf.GetWPos()
+	count := buf.GetContext().messageTypeRefCount
+	err = WriteFrame(buf, 0, &GenericMessage{Name: "motan.Other"}, make(chan int))
+	if err == nil || buf.GetWPos() != pos || buf.GetContext().messageTypeRefCount != count {
+		t.Errorf("write frame should rollback. err:%v, wpos:%d, count:%d", err, buf.GetWPos(), buf.GetContext().messageTypeRefCount)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	buf := CreateBuffer(valuesFrameFixture)
+	frame, err := ReadFrame(buf)
+	if err != nil || frame.Version != FrameVersion || frame.Flags != 0 || buf.Remain() != 0 {
+		t.Fatalf("read frame fail. err:%v, frame:%+v", err, frame)
+	}
+	expects := []interface{}{"hello", int32(1), map[string]int32{"a": 2}}
+	for _, expect := range expects {
+		v, err := ReadValue(frame.Payload, reflect.TypeOf(expect))
+		if err != nil || !reflect.DeepEqual(v, expect) {
+			t.Errorf("read frame value not correct. err:%v, expect:%v, real:%v", err, expect, v)
+		}
+	}
+
+	buf = CreateBuffer(messageFramesFixture)
+	for i := 0; i < 2; i++ {
+		frame, err = ReadFrame(buf)
+		if err != nil {
+			t.Fatalf("read frame fail. err:%v", err)
+		}
+		v, err := ReadValue(frame.Payload, nil)
+		if err != nil || v.(*GenericMessage).GetName() != "motan.Fx" || v.(*GenericMessage).GetFieldByIndex(1) != "x" {
+			t.Errorf("read frame message not correct. err:%v, message:%v", err, v)
+		}
+	}
+
+	// incomplete frame
+	for _, data := range [][]byte{valuesFrameFixture[:5], valuesFrameFixture[:len(valuesFrameFixture)-1]} {
+		buf = CreateBuffer(data)
+		if _, err = ReadFrame(buf); err != ErrNotEnough || buf.GetRPos() != 0 {
+			t.Errorf("incomplete frame should return ErrNotEnough. err:%v, rpos:%d", err, buf.GetRPos())
+		}
+	}
+	data := append([]byte{}, valuesFrameFixture...)
+	data[0] = 0
+	if _, err = ReadFrame(CreateBuffer(data)); err != ErrWrongFrameMagic {
+		t.Errorf("should return ErrWrongFrameMagic. err:%v", err)
+	}
+	data[0], data[2] = 0x42, 2
+	if _, err = ReadFrame(CreateBuffer(data)); err != ErrWrongFrameVersion {
+		t.Errorf("should return ErrWrongFrameVersion. err:%v", err)
+	}
+
+	// the payload is read with the limits and strict mode of buf
+	buf = CreateBuffer(valuesFrameFixture)
+	buf.SetDecodeLimits(&DecodeLimits{MaxStringLength: 3})
+	buf.SetStrict(true)
+	frame, _ = ReadFrame(buf)
+	var s string
+	if err = ReadString(frame.Payload, &s); !errors.As(err, new(*LimitError)) {
+		t.Errorf("limits should be enforced in payload. err:%v", err)
+	}
+	if err = ReadString(frame.Payload, &s); !errors.As(err, new(*TypeMismatchError)) {
+		t.Errorf("payload should be read in strict mode. err:%v", err)
+	}
+}
@@ -0,0 +1,181 @@
+//go:build go1.18
+// +build go1.18
+
+package breeze
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// fuzzSeeds get the seed payloads of fuzz tests from the values used in other tests
+func fuzzSeeds() [][]byte {
+	values := []interface{}{true, "string", byte(3), []byte("bytes"), int16(-12), int32(1234), int64(-456789),
+		float32(1.23), float64(-4.56), []string{"a", "b"}, []int32{1, 2}, map[string]int64{"k": 1},
+		map[interface{}]interface{}{"a": int32(1), int64(2): []interface{}{"x", true}},
+		getTestMsg(), getTestSubMsg(), toStructMsg(getTestMsg())}
+	var seeds [][]byte
+	for _, v := range values {
+		buf := NewBuffer(256)
+		WriteValue(buf, v)
+		seeds = append(seeds, buf.Bytes())
+	}
+	// payload with schema and message type refs
+	buf := NewBuffer(256)
+	buf.SetWriteSchema(true)
+	WriteValue(buf, []interface{}{getTestMsg(), getTestMsg()})
+	seeds = append(seeds, buf.Bytes())
+	// malformed payloads found before
+	seeds = append(seeds, []byte{MapType, 1, ArrayType, 1, 0x01, 'a', 0x01, 'b'}, []byte{0, 0, 0, 2, 1, StringType, 3, 'a', 'b', 'c'})
+	return seeds
+}
+
+func addSeeds(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(seed)
+	}
+}
+
+func FuzzReadValue(f *testing.F) {
+	addSeeds(f)
+	types := []interface{}{nil, &TestMsg{}, &testStructMsg{}, reflect.TypeOf(map[string][]int32{}),
+		reflect.TypeOf([]interface{}{}), reflect.TypeOf(""), new(int64), new(map[string]*TestSubMsg)}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, v := range types {
+			buf := CreateBuffer(data)
+			buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64, MaxTotalAlloc: 1 << 20})
+			ReadValue(buf, v)
+		}
+	})
+}
+
+func FuzzReadMessageField(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := CreateBuffer(data)
+		ReadMessageField(buf, func(buf *Buffer, index int) error {
+			_, err := ReadValue(buf, nil)
+			return err
+		})
+		if buf.GetRPos() > len(data) || buf.Remain() < 0 {
+			t.Fatalf("wrong buffer position after read. rpos:%d, len:%d", buf.GetRPos(), len(data))
+		}
+	})
+}
+
+func FuzzReadPacked(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, isMap := range []bool{true, false} {
+			buf := CreateBuffer(data)
+			size, err := ReadPackedSize(buf, false)
+			if err != nil {
+				continue
+			}
+			ReadPacked(buf, size, isMap, func(buf *Buffer) error {
+				_, err := ReadStringWithoutType(buf)
+				if err == nil && isMap {
+					_, err = ReadValue(buf, nil)
+				}
+				return err
+			})
+		}
+	})
+}
+
+func FuzzReadWithoutType(f *testing.F) {
+	addSeeds(f)
+	readers := []func(buf *Buffer) error{
+		func(buf *Buffer) error { _, err := ReadBoolWithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadStringWithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadBytesWithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadInt16WithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadInt32WithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadInt64WithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadFloat32WithoutType(buf); return err },
+		func(buf *Buffer) error { _, err := ReadFloat64WithoutType(buf); return err },
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, read := range readers {
+			buf := CreateBuffer(data)
+			if read(buf) == nil && buf.Remain() < 0 {
+				t.Fatalf("read beyond buffer. rpos:%d, len:%d", buf.GetRPos(), len(data))
+			}
+		}
+	})
+}
+
+func FuzzSkipValue(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := CreateBuffer(data)
+		buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+		_, rerr := ReadValue(buf, nil)
+		sbuf := CreateBuffer(data)
+		sbuf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+		serr := SkipValue(sbuf)
+		if rerr == nil && (serr != nil || sbuf.GetRPos() != buf.GetRPos()) {
+			t.Fatalf("skip should stop at the end of value. err:%v, expect pos:%d, real pos:%d", serr, buf.GetRPos(), sbuf.GetRPos())
+		}
+	})
+}
+
+func FuzzReadRawValue(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := CreateBuffer(data)
+		buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+		raw, err := ReadRawValue(buf)
+		if err != nil {
+			return
+		}
+		out := NewBuffer(len(data))
+		if err = WriteValue(out, raw); err != nil {
+			t.Fatalf("write raw value fail. err:%v", err)
+		}
+		if !raw.hasMessage && !bytes.HasSuffix(data[:buf.GetRPos()], out.Bytes()) { // the schemas before value are not written
+			t.Fatalf("raw value should be written verbatim. expect:%x, real:%x", data[:buf.GetRPos()], out.Bytes())
+		}
+	})
+}
+
+func FuzzExtract(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := CreateBuffer(data)
+		buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+		_, rerr := ReadValue(buf, nil)
+		ebuf := CreateBuffer(data)
+		ebuf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+		_, eerr := Extract(ebuf, "1", "[*]", "3[*].2", "4[0]", "5")
+		if rerr == nil && (eerr != nil || ebuf.GetRPos() != buf.GetRPos()) {
+			t.Fatalf("extract should stop at the end of value. err:%v, expect pos:%d, real pos:%d", eerr, buf.GetRPos(), ebuf.GetRPos())
+		}
+	})
+}
+
+func FuzzPatchField(f *testing.F) {
+	addSeeds(f)
+	other := &GenericMessage{Name: "motan.Other"}
+	other.PutField(1, "other")
+	values := []interface{}{nil, int32(7), other}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := CreateBuffer(data)
+		buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+		if _, err := ReadValue(buf, nil); err != nil {
+			return
+		}
+		for _, v := range values {
+			patched, err := PatchField(data, "1", v)
+			if err != nil {
+				continue
+			}
+			pbuf := CreateBuffer(patched)
+			pbuf.SetDecodeLimits(&DecodeLimits{MaxDepth: 64})
+			if _, err = ReadValue(pbuf, nil); err != nil {
+				t.Fatalf("patched message should be read. err:%v, patched:%x", err, patched)
+			}
+		}
+	})
+}
@@ -0,0 +1,726 @@
+package breeze
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// JSONOptions is the options of converting breeze values to json
+type JSONOptions struct {
+	Int64AsString bool // write int64 values as json strings, because json numbers lose precision beyond 2^53 in many languages
+}
+
+// ToJSON read a breeze value from the buffer and convert it to json with default options. see JSONOptions.ToJSON
+func ToJSON(buf *Buffer, schemas ...*Schema) ([]byte, error) {
+	return JSONOptions{}.ToJSON(buf, schemas...)
+}
+
+/*
+ToJSON read a breeze value from the buffer and convert it to json.
+
+The schemas of messages are found in schemas, the context of buffer and the registered messages and enums by message name.
+The fields of a message are written as json object keys by field names if the schema of message is found, otherwise by field indexes.
+Enums are written as the names of enum values if the schema of enum has enum values, bytes are written as base64 strings.
+*/
+func (o JSONOptions) ToJSON(buf *Buffer, schemas ...*Schema) ([]byte, error) {
+	j := &jsonTranscoder{options: o, schemas: schemas, context: buf.GetContext()}
+	out := &bytes.Buffer{}
+	tp, name, err := readType(buf)
+	if err != nil {
+		return nil, err
+	}
+	if err = j.toJSON(out, buf, tp, name); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+/*
+FromJSON convert json to a breeze message which is described by schema, and return the breeze bytes.
+
+The schemas of nested messages are found in schema and schemas by the field types, then in the registered messages and enums.
+The json object keys of a message can be field names or field indexes. The types of the fields which are not in schema are inferred
+from json values: strings, int32 or int64 for integers, float64, bool, arrays and maps. Enums can be names or numbers,
+bytes are base64 strings, int64 values can be json numbers or strings. The json null values of fields are ignored.
+If schema is nil, the json is converted as an inferred value.
+*/
+func FromJSON(data []byte, schema *Schema, schemas ...*Schema) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, errors.New("breeze: invalid json, " + err.Error())
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("breeze: invalid json, unexpected data after json value")
+	}
+	buf := NewBuffer(len(data))
+	var err error
+	if schema == nil {
+		err = WriteValue(buf, jsonValue(v))
+	} else {
+		j := &jsonTranscoder{schemas: append([]*Schema{schema}, schemas...)}
+		err = j.fromJSON(buf, v, &fieldType{name: schema.Name}, "", shortName(schema.Name), true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type jsonTranscoder struct {
+	options JSONOptions
+	schemas []*Schema
+	context *Context
+}
+
+// findSchema find the schema of message or enum by name. the name without package is also found in pkg,
+// because the message types of fields can be declared without package.
+func (j *jsonTranscoder) findSchema(name string, pkg string) *Schema {
+	names := []string{name}
+	if pkg != "" && !strings.Contains(name, ".") {
+		names = []string{pkg + "." + name, name}
+	}
+	for _, n := range names {
+		for _, s := range j.schemas {
+			if s != nil && (s.Name == n || s.Alias == n) {
+				return s
+			}
+		}
+		if j.context != nil {
+			if s := j.context.GetSchema(n); s != nil {
+				return s
+			}
+		}
+		if s := getRegisteredSchema(n); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+//========== breeze to json =====================
+
+// toJSON convert a breeze value which type is tp to json
+func (j *jsonTranscoder) toJSON(out *bytes.Buffer, buf *Buffer, tp byte, name string) error {
+	switch tp {
+	case MessageType:
+		return j.messageToJSON(out, buf, name)
+	case MapType, PackedMapType:
+		return j.mapToJSON(out, buf, tp == PackedMapType)
+	case ArrayType, PackedArrayType:
+		return j.arrayToJSON(out, buf, tp == PackedArrayType)
+	}
+	v, err := readValueByType(buf, nil, false, tp, name)
+	if err != nil {
+		return err
+	}
+	return j.writeJSON(out, v)
+}
+
+func (j *jsonTranscoder) messageToJSON(out *bytes.Buffer, buf *Buffer, name string) error {
+	schema := j.findSchema(name, "")
+	if schema != nil && schema.IsEnum() {
+		return j.enumToJSON(out, buf, name, schema)
+	}
+	setReadingMessage(buf, name, schema)
+	out.WriteByte('{')
+	first := true
+	err := ReadMessageField(buf, func(buf *Buffer, index int) error {
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+		key := strconv.Itoa(index)
+		if schema != nil {
+			if field := schema.GetFieldByIndex(index); field != nil {
+				key = field.Name
+			}
+		}
+		writeJSONString(out, key)
+		out.WriteByte(':')
+		tp, name, err := readType(buf)
+		if err != nil {
+			return err
+		}
+		return j.toJSON(out, buf, tp, name)
+	})
+	out.WriteByte('}')
+	return err
+}
+
+// enumToJSON write the name of enum value, the number is written if the name is not found
+func (j *jsonTranscoder) enumToJSON(out *bytes.Buffer, buf *Buffer, name string, schema *Schema) error {
+	var number int32
+	setReadingMessage(buf, name, schema)
+	err := ReadMessageField(buf, func(buf *Buffer, index int) error {
+		if index == 1 {
+			return ReadInt32(buf, &number)
+		}
+		return SkipValue(buf)
+	})
+	if err != nil {
+		return err
+	}
+	if s, ok := schema.GetEnumName(int(number)); ok {
+		writeJSONString(out, s)
+	} else {
+		out.WriteString(strconv.Itoa(int(number)))
+	}
+	return nil
+}
+
+func (j *jsonTranscoder) mapToJSON(out *bytes.Buffer, buf *Buffer, isPacked bool) error {
+	size, err := ReadPackedSize(buf, false)
+	if err != nil {
+		return err
+	}
+	out.WriteByte('{')
+	if size > 0 {
+		if err = enterRead(buf); err != nil {
+			return err
+		}
+		defer leaveRead(buf)
+	}
+	var ktp, vtp byte
+	var kn, vn string
+	if isPacked && size > 0 {
+		if ktp, kn, err = readType(buf); err != nil {
+			return err
+		}
+		if vtp, vn, err = readType(buf); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < size; i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		etp := ktp
+		if isPacked {
+			etp, err = packedElemType(buf, ktp)
+		} else {
+			etp, kn, err = readType(buf)
+		}
+		if err != nil {
+			return err
+		}
+		if etp == MessageType || etp == MapType || etp == PackedMapType || etp == ArrayType || etp == PackedArrayType {
+			return newTypeMismatchError(buf, "json object key", TypeName(etp))
+		}
+		k, err := readValueByType(buf, nil, false, etp, kn)
+		if err != nil {
+			return err
+		}
+		if s, ok := k.(string); ok {
+			writeJSONString(out, s)
+		} else if s, ok := k.([]byte); ok {
+			writeJSONString(out, base64.StdEncoding.EncodeToString(s))
+		} else {
+			kb := &bytes.Buffer{}
+			if err = j.writeJSON(kb, k); err != nil {
+				return err
+			}
+			writeJSONString(out, strings.Trim(kb.String(), `"`))
+		}
+		out.WriteByte(':')
+		if isPacked {
+			etp, err = packedElemType(buf, vtp)
+		} else {
+			etp, vn, err = readType(buf)
+		}
+		if err != nil {
+			return err
+		}
+		if err = j.toJSON(out, buf, etp, vn); err != nil {
+			return withPath(err, keySegment(k))
+		}
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+func (j *jsonTranscoder) arrayToJSON(out *bytes.Buffer, buf *Buffer, isPacked bool) error {
+	size, err := ReadPackedSize(buf, false)
+	if err != nil {
+		return err
+	}
+	out.WriteByte('[')
+	if size > 0 {
+		if err = enterRead(buf); err != nil {
+			return err
+		}
+		defer leaveRead(buf)
+	}
+	var tp byte
+	var name string
+	if isPacked && size > 0 {
+		if tp, name, err = readType(buf); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < size; i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		etp := tp
+		if isPacked {
+			etp, err = packedElemType(buf, tp)
+		} else {
+			etp, name, err = readType(buf)
+		}
+		if err != nil {
+			return err
+		}
+		if err = j.toJSON(out, buf, etp, name); err != nil {
+			return withPath(err, indexSegment(i))
+		}
+	}
+	out.WriteByte(']')
+	return nil
+}
+
+// writeJSON write a basic breeze value as json
+func (j *jsonTranscoder) writeJSON(out *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		out.WriteString("null")
+	case bool:
+		out.WriteString(strconv.FormatBool(value))
+	case string:
+		writeJSONString(out, value)
+	case []byte:
+		writeJSONString(out, base64.StdEncoding.EncodeToString(value))
+	case byte:
+		out.WriteString(strconv.Itoa(int(value)))
+	case int16:
+		out.WriteString(strconv.Itoa(int(value)))
+	case int32:
+		out.WriteString(strconv.Itoa(int(value)))
+	case int64:
+		if j.options.Int64AsString {
+			writeJSONString(out, strconv.FormatInt(value, 10))
+		} else {
+			out.WriteString(strconv.FormatInt(value, 10))
+		}
+	case float32:
+		return writeJSONFloat(out, float64(value), 32)
+	case float64:
+		return writeJSONFloat(out, value, 64)
+	default:
+		return errors.New("breeze: can not convert " + targetName(v) + " to json")
+	}
+	return nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	out.Write(b)
+}
+
+func writeJSONFloat(out *bytes.Buffer, f float64, bitSize int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return errors.New("breeze: can not convert " + strconv.FormatFloat(f, 'g', -1, bitSize) + " to json")
+	}
+	out.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	return nil
+}
+
+//========== json to breeze =====================
+
+// fieldType is a parsed field type of schema, such as int32, TestSubMsg and map<string, array<int32>>
+type fieldType struct {
+	name  string // basic type name, message or enum name, map or array
+	key   *fieldType
+	value *fieldType
+}
+
+func (t *fieldType) String() string {
+	switch t.name {
+	case "map":
+		return "map<" + t.key.String() + ", " + t.value.String() + ">"
+	case "array":
+		return "array<" + t.value.String() + ">"
+	}
+	return t.name
+}
+
+// parseFieldType parse the type of schema field
+func parseFieldType(s string) (*fieldType, error) {
+	t, rest, err := parseFieldTypePrefix(s)
+	if err == nil && strings.TrimSpace(rest) != "" {
+		err = errors.New("breeze: wrong field type " + s)
+	}
+	return t, err
+}
+
+func parseFieldTypePrefix(s string) (*fieldType, string, error) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexAny(s, "<>, ")
+	if end < 0 {
+		end = len(s)
+	}
+	t := &fieldType{name: s[:end]}
+	rest := strings.TrimSpace(s[end:])
+	if t.name == "" {
+		return nil, rest, errors.New("breeze: empty field type")
+	}
+	if t.name != "map" && t.name != "array" {
+		return t, rest, nil
+	}
+	if !strings.HasPrefix(rest, "<") {
+		return nil, rest, errors.New("breeze: missing element type of " + t.name)
+	}
+	var err error
+	if t.name == "map" {
+		if t.key, rest, err = parseFieldTypePrefix(rest[1:]); err != nil {
+			return nil, rest, err
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, rest, errors.New("breeze: missing value type of map")
+		}
+	}
+	if t.value, rest, err = parseFieldTypePrefix(rest[1:]); err != nil {
+		return nil, rest, err
+	}
+	if !strings.HasPrefix(rest, ">") {
+		return nil, rest, errors.New("breeze: missing '>' of " + t.name)
+	}
+	return t, rest[1:], nil
+}
+
+// fromJSON write json value v as breeze type t. pkg is the package of the message which v belongs to, it is used to find the schemas of messages
+func (j *jsonTranscoder) fromJSON(buf *Buffer, v interface{}, t *fieldType, pkg string, path string, withType bool) error {
+	switch t.name {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return jsonMismatch(v, t, path)
+		}
+		WriteString(buf, s, withType)
+	case "bytes":
+		s, ok := v.(string)
+		if !ok {
+			return jsonMismatch(v, t, path)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return errors.New("breeze: wrong base64 bytes, path " + path)
+		}
+		WriteBytes(buf, b, withType)
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return jsonMismatch(v, t, path)
+		}
+		WriteBool(buf, b, withType)
+	case "byte", "int16", "int32", "int64":
+		i, err := jsonInt(v, t, path)
+		if err != nil {
+			return err
+		}
+		switch t.name {
+		case "byte":
+			WriteByte(buf, byte(i), withType)
+		case "int16":
+			WriteInt16(buf, int16(i), withType)
+		case "int32":
+			WriteInt32(buf, int32(i), withType)
+		default:
+			WriteInt64(buf, i, withType)
+		}
+	case "float32", "float64":
+		n, ok := v.(json.Number)
+		if !ok {
+			return jsonMismatch(v, t, path)
+		}
+		bitSize := 64
+		if t.name == "float32" {
+			bitSize = 32
+		}
+		f, err := strconv.ParseFloat(string(n), bitSize)
+		if err != nil {
+			return errors.New("breeze: can not convert json number " + string(n) + " to " + t.name + ", path " + path)
+		}
+		if bitSize == 32 {
+			WriteFloat32(buf, float32(f), withType)
+		} else {
+			WriteFloat64(buf, f, withType)
+		}
+	case "map":
+		return j.mapFromJSON(buf, v, t, pkg, path, withType)
+	case "array":
+		return j.arrayFromJSON(buf, v, t, pkg, path, withType)
+	default:
+		return j.messageFromJSON(buf, v, t, pkg, path, withType)
+	}
+	return nil
+}
+
+func (j *jsonTranscoder) mapFromJSON(buf *Buffer, v interface{}, t *fieldType, pkg string, path string, withType bool) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return jsonMismatch(v, t, path)
+	}
+	if withType {
+		WritePackedMapType(buf)
+	}
+	buf.WriteVarInt(uint64(len(m)))
+	if len(m) == 0 {
+		return nil
+	}
+	j.writeType(buf, t.key, pkg)
+	j.writeType(buf, t.value, pkg)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var key interface{} = k
+		switch t.key.name {
+		case "string", "bytes":
+		case "bool":
+			b, err := strconv.ParseBool(k)
+			if err != nil {
+				return errors.New("breeze: can not convert json key " + strconv.Quote(k) + " to bool, path " + path)
+			}
+			key = b
+		default:
+			key = json.Number(k)
+		}
+		if err := j.fromJSON(buf, key, t.key, pkg, path, false); err != nil {
+			return err
+		}
+		if err := j.fromJSON(buf, m[k], t.value, pkg, path+keySegment(k), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonTranscoder) arrayFromJSON(buf *Buffer, v interface{}, t *fieldType, pkg string, path string, withType bool) error {
+	a, ok := v.([]interface{})
+	if !ok {
+		return jsonMismatch(v, t, path)
+	}
+	if withType {
+		WritePackedArrayType(buf)
+	}
+	buf.WriteVarInt(uint64(len(a)))
+	if len(a) == 0 {
+		return nil
+	}
+	j.writeType(buf, t.value, pkg)
+	for i, e := range a {
+		if err := j.fromJSON(buf, e, t.value, pkg, path+indexSegment(i), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *jsonTranscoder) messageFromJSON(buf *Buffer, v interface{}, t *fieldType, pkg string, path string, withType bool) error {
+	schema := j.findSchema(t.name, pkg)
+	name := t.name
+	if schema != nil {
+		name = schema.Name
+	}
+	if withType {
+		WriteMessageType(buf, name)
+	}
+	pos := skipLength(buf)
+	if schema != nil && schema.IsEnum() {
+		var number int
+		if s, ok := v.(string); ok {
+			if number, ok = schema.GetEnumNumber(s); !ok {
+				return errors.New("breeze: unknown enum value " + strconv.Quote(s) + " of " + name + ", path " + path)
+			}
+		} else {
+			i, err := jsonInt(v, &fieldType{name: "int32"}, path)
+			if err != nil {
+				return err
+			}
+			number = int(i)
+		}
+		WriteInt32Field(buf, 1, int32(number))
+		writeLength(buf, pos)
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return jsonMismatch(v, t, path)
+	}
+	type jsonField struct {
+		index int
+		key   string
+		tp    *fieldType
+	}
+	fields := make([]jsonField, 0, len(m))
+	for k, fv := range m {
+		if fv == nil {
+			continue
+		}
+		var field *Field
+		if schema != nil {
+			field = schema.GetFieldByName(k)
+		}
+		if field == nil {
+			index, err := strconv.Atoi(k)
+			if err != nil || index < 0 {
+				return errors.New("breeze: unknown field " + strconv.Quote(k) + " of " + name + ", path " + path)
+			}
+			if schema != nil {
+				field = schema.GetFieldByIndex(index)
+			}
+			if field == nil {
+				fields = append(fields, jsonField{index: index, key: k})
+				continue
+			}
+		}
+		tp, err := parseFieldType(field.Type)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, jsonField{index: field.Index, key: k, tp: tp})
+	}
+	sort.Slice(fields, func(a, b int) bool { return fields[a].index < fields[b].index })
+	msgPkg := ""
+	if i := strings.LastIndex(name, "."); i > 0 {
+		msgPkg = name[:i]
+	}
+	for i, field := range fields {
+		if i > 0 && field.index == fields[i-1].index {
+			return errors.New("breeze: duplicate field index " + strconv.Itoa(field.index) + " of " + name + ", path " + path)
+		}
+		buf.WriteVarInt(uint64(field.index))
+		var err error
+		if field.tp == nil {
+			err = WriteValue(buf, jsonValue(m[field.key]))
+		} else {
+			err = j.fromJSON(buf, m[field.key], field.tp, msgPkg, path+"."+field.key, true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	writeLength(buf, pos)
+	return nil
+}
+
+// writeType write the breeze type of elements in packed map or packed array
+func (j *jsonTranscoder) writeType(buf *Buffer, t *fieldType, pkg string) {
+	switch t.name {
+	case "string":
+		WriteStringType(buf)
+	case "bytes":
+		WriteBytesType(buf)
+	case "bool":
+		WriteBoolType(buf)
+	case "byte":
+		WriteByteType(buf)
+	case "int16":
+		WriteInt16Type(buf)
+	case "int32":
+		WriteInt32Type(buf)
+	case "int64":
+		WriteInt64Type(buf)
+	case "float32":
+		WriteFloat32Type(buf)
+	case "float64":
+		WriteFloat64Type(buf)
+	case "map":
+		WritePackedMapType(buf)
+	case "array":
+		WritePackedArrayType(buf)
+	default:
+		name := t.name
+		if schema := j.findSchema(t.name, pkg); schema != nil {
+			name = schema.Name
+		}
+		WriteMessageType(buf, name)
+	}
+}
+
+// jsonInt get the integer of json number or string in the range of type t
+func jsonInt(v interface{}, t *fieldType, path string) (int64, error) {
+	var s string
+	switch value := v.(type) {
+	case json.Number:
+		s = string(value)
+	case string:
+		s = value
+	default:
+		return 0, jsonMismatch(v, t, path)
+	}
+	bitSize := 64
+	switch t.name {
+	case "byte":
+		bitSize = 8
+	case "int16":
+		bitSize = 16
+	case "int32":
+		bitSize = 32
+	}
+	if bitSize == 8 {
+		i, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return 0, errors.New("breeze: can not convert json " + s + " to byte, path " + path)
+		}
+		return int64(i), nil
+	}
+	i, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		return 0, errors.New("breeze: can not convert json " + s + " to " + t.name + ", path " + path)
+	}
+	return i, nil
+}
+
+func jsonMismatch(v interface{}, t *fieldType, path string) error {
+	kind := "object"
+	switch v.(type) {
+	case nil:
+		kind = "null"
+	case bool:
+		kind = "bool"
+	case string:
+		kind = "string"
+	case json.Number:
+		kind = "number"
+	case []interface{}:
+		kind = "array"
+	}
+	return errors.New("breeze: can not convert json " + kind + " to " + t.String() + ", path " + path)
+}
+
+// jsonValue convert a json value without schema to the value to be written. json numbers are converted to int32, int64 or float64
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return int32(i)
+			}
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case []interface{}:
+		for i, e := range value {
+			value[i] = jsonValue(e)
+		}
+	case map[string]interface{}:
+		for k, e := range value {
+			value[k] = jsonValue(e)
+		}
+	}
+	return v
+}
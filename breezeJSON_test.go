@@ -0,0 +1,136 @@
+package breeze
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func getTestJSONSchemas() []*Schema {
+	enumSchema := &Schema{Name: "motan.MyEnum"}
+	enumSchema.PutFields(&Field{Index: 1, Name: "enumNumber", Type: "int32"})
+	enumSchema.PutEnumValue(1, "E1")
+	enumSchema.PutEnumValue(2, "E2")
+	enumSchema.PutEnumValue(3, "E3")
+	return []*Schema{testMsgBreezeSchema, testSubMsgBreezeSchema, enumSchema}
+}
+
+func TestToJSON(t *testing.T) {
+	buf := NewBuffer(256)
+	WriteValue(buf, getTestMsg())
+	data, err := ToJSON(CreateBuffer(buf.Bytes()), getTestJSONSchemas()...)
+	if err != nil {
+		t.Fatalf("to json fail. err:%v", err)
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("wrong json. err:%v, json:%s", err, data)
+	}
+	sub := m["myMap"].(map[string]interface{})["m1"].(map[string]interface{})
+	if m["myInt"] != 12.0 || m["myEnum"] != "E3" || !reflect.DeepEqual(m["enumArray"], []interface{}{"E3", "E2"}) ||
+		sub["myBytes"] != "aXBvd2Vy" || sub["myInt64"] != 234.0 || sub["myFloat32"] != 23.434 ||
+		!reflect.DeepEqual(sub["myMap2"].(map[string]interface{})["12"], []interface{}{34.0, -15.0}) {
+		t.Errorf("wrong json: %s", data)
+	}
+
+	// int64 as string
+	data, err = JSONOptions{Int64AsString: true}.ToJSON(CreateBuffer(buf.Bytes()), getTestJSONSchemas()...)
+	if err != nil || !strings.Contains(string(data), `"myInt64":"234"`) {
+		t.Errorf("int64 should be string. err:%v, json:%s", err, data)
+	}
+
+	// numeric keys without schemas, enum without enum values
+	data, err = ToJSON(CreateBuffer(buf.Bytes()))
+	if err != nil || !strings.HasPrefix(string(data), `{"1":12,"2":"jiernoce",`) || !strings.Contains(string(data), `"6":{"1":3}`) {
+		t.Errorf("fields should be numeric keys. err:%v, json:%s", err, data)
+	}
+
+	// basic values
+	buf = NewBuffer(64)
+	WriteValue(buf, map[int64][]byte{-5: []byte("ab")})
+	if data, err = ToJSON(buf); err != nil || string(data) != `{"-5":"YWI="}` {
+		t.Errorf("wrong json. err:%v, json:%s", err, data)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	schemas := getTestJSONSchemas()
+	buf := NewBuffer(256)
+	WriteValue(buf, getTestMsg())
+	data, err := ToJSON(CreateBuffer(buf.Bytes()), schemas...)
+	if err != nil {
+		t.Fatalf("to json fail. err:%v", err)
+	}
+	b, err := FromJSON(data, schemas[0], schemas...)
+	if err != nil {
+		t.Fatalf("from json fail. err:%v", err)
+	}
+	result := &TestMsg{}
+	if _, err = ReadValue(CreateBuffer(b), result); err != nil || !reflect.DeepEqual(getTestMsg(), result) {
+		t.Errorf("wrong message from json. err:%v, message:%+v", err, result)
+	}
+
+	// field indexes, int64 strings, enum numbers, inferred fields and null fields
+	src := `{"1": 5, "subMsg": {"myInt64": "9007199254740993", "12": [1, "a"]}, "myEnum": 2, "100": {"x": 1.5}, "myString": null}`
+	if b, err = FromJSON([]byte(src), schemas[0], schemas...); err != nil {
+		t.Fatalf("from json fail. err:%v", err)
+	}
+	v, err := ReadValue(CreateBuffer(b), nil)
+	if err != nil {
+		t.Fatalf("read fail. err:%v", err)
+	}
+	msg := v.(*GenericMessage)
+	subMsg := msg.GetFieldByIndex(5).(*GenericMessage)
+	if msg.Name != "motan.TestMsg" || msg.GetFieldByIndex(1) != int32(5) || msg.GetFieldByIndex(2) != nil || subMsg.Name != "motan.TestSubMsg" ||
+		subMsg.GetFieldByIndex(3) != int64(9007199254740993) || !reflect.DeepEqual(subMsg.GetFieldByIndex(12), []interface{}{1, "a"}) ||
+		msg.GetFieldByIndex(6).(*GenericMessage).GetFieldByIndex(1) != int32(2) || !reflect.DeepEqual(msg.GetFieldByIndex(100), map[interface{}]interface{}{"x": 1.5}) {
+		t.Errorf("wrong message from json: %+v", msg)
+	}
+
+	// without schema
+	if b, err = FromJSON([]byte(`[true, 3000000000]`), nil); err != nil {
+		t.Fatalf("from json fail. err:%v", err)
+	}
+	if v, err = ReadValue(CreateBuffer(b), nil); err != nil || !reflect.DeepEqual(v, []interface{}{true, int64(3000000000)}) {
+		t.Errorf("wrong value from json. err:%v, value:%v", err, v)
+	}
+}
+
+func TestFromJSONError(t *testing.T) {
+	schemas := getTestJSONSchemas()
+	tests := []struct {
+		src    string
+		errMsg string
+	}{
+		{`{"myInt": "x"}`, "breeze: can not convert json x to int32, path TestMsg.myInt"},
+		{`{"myInt": 3000000000}`, "breeze: can not convert json 3000000000 to int32, path TestMsg.myInt"},
+		{`{"myMap": {"k": {"myBytes": "%%"}}}`, `breeze: wrong base64 bytes, path TestMsg.myMap["k"].myBytes`},
+		{`{"myArray": [{"myMap2": {"x": []}}]}`, "breeze: can not convert json x to int32, path TestMsg.myArray[0].myMap2"},
+		{`{"enumArray": ["E4"]}`, `breeze: unknown enum value "E4" of motan.MyEnum, path TestMsg.enumArray[0]`},
+		{`{"notExist": 1}`, `breeze: unknown field "notExist" of motan.TestMsg, path TestMsg`},
+		{`{"myInt": 1, "1": 2}`, "breeze: duplicate field index 1 of motan.TestMsg, path TestMsg"},
+		{`[]`, "breeze: can not convert json array to motan.TestMsg, path TestMsg"},
+		{`{"myInt": 1} {}`, "breeze: invalid json, unexpected data after json value"},
+		{`{"myInt": 1`, "breeze: invalid json, unexpected EOF"},
+	}
+	for _, test := range tests {
+		_, err := FromJSON([]byte(test.src), schemas[0], schemas...)
+		if err == nil || err.Error() != test.errMsg {
+			t.Errorf("wrong error. json:%s, expect:%s, real:%v", test.src, test.errMsg, err)
+		}
+	}
+}
+
+func TestParseFieldType(t *testing.T) {
+	for _, s := range []string{"int32", "motan.TestSubMsg", "array<MyEnum>", "map<string, map<int32, array<bytes>>>"} {
+		if ft, err := parseFieldType(s); err != nil || ft.String() != s {
+			t.Errorf("wrong field type. expect:%s, real:%v, err:%v", s, ft, err)
+		}
+	}
+	for _, s := range []string{"", "map<string>", "array<int32", "array int32", "map<string, int32>>"} {
+		if _, err := parseFieldType(s); err == nil {
+			t.Errorf("should fail to parse field type %q", s)
+		}
+	}
+}
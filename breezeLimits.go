@@ -0,0 +1,113 @@
+package breeze
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// estimated allocation of a collection element, it is used to count the total allocation of reading
+const elemAllocSize = 16
+
+// DecodeLimits limits the resources used in reading, it should be set when reading untrusted data.
+// zero value of a limit means unlimited.
+type DecodeLimits struct {
+	MaxDepth        int // max nesting depth of messages, maps and arrays
+	MaxBytesLength  int // max length of a bytes value
+	MaxStringLength int // max length of a string value, including message names and schemas
+	MaxTotalAlloc   int // max estimated bytes allocated by strings, bytes and collections since the limits set or the buffer reset
+}
+
+// LimitError is returned when a value read from buffer exceeds the DecodeLimits
+type LimitError struct {
+	Limit  string // name of the exceeded limit, such as MaxDepth
+	Max    int
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return "breeze: decode limit " + e.Limit + " exceeded. max:" + strconv.Itoa(e.Max) + ", actual:" + strconv.Itoa(e.Actual)
+}
+
+// SetDecodeLimits set the limits of reading from the buffer, nil means unlimited
+func (b *Buffer) SetDecodeLimits(limits *DecodeLimits) {
+	b.limits = limits
+	b.readAlloc = 0
+}
+
+// GetDecodeLimits get the limits of reading from the buffer
+func (b *Buffer) GetDecodeLimits() *DecodeLimits {
+	return b.limits
+}
+
+// enterRead check the read depth before reading a nested message, map or array.
+// leaveRead must be called after the value is read if no error returned.
+func enterRead(buf *Buffer) error {
+	if buf.limits != nil && buf.limits.MaxDepth > 0 && buf.readDepth >= buf.limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Max: buf.limits.MaxDepth, Actual: buf.readDepth + 1}
+	}
+	buf.readDepth++
+	return nil
+}
+
+func leaveRead(buf *Buffer) {
+	buf.readDepth--
+}
+
+// checkElemSize check the element size of a collection before it is created
+func checkElemSize(buf *Buffer, size uint64) (int, error) {
+	if size > uint64(MaxElemSize) {
+		return 0, errors.New("breeze: collection elem size overflow. size:" + strconv.FormatUint(size, 10))
+	}
+	if err := checkAlloc(buf, int(size)*elemAllocSize); err != nil {
+		return 0, err
+	}
+	if size > uint64(buf.Remain()) { // every element takes one byte at least
+		return 0, ErrNotEnough
+	}
+	return int(size), nil
+}
+
+// checkLength check the length of a string or bytes value before it is read
+func checkLength(buf *Buffer, size uint64, isString bool) (int, error) {
+	if buf.limits != nil {
+		limit, max := "MaxBytesLength", buf.limits.MaxBytesLength
+		if isString {
+			limit, max = "MaxStringLength", buf.limits.MaxStringLength
+		}
+		if max > 0 && size > uint64(max) {
+			return 0, &LimitError{Limit: limit, Max: max, Actual: clampInt(size)}
+		}
+	}
+	if size > uint64(buf.Remain()) {
+		return 0, ErrNotEnough
+	}
+	return int(size), checkAlloc(buf, int(size))
+}
+
+func checkAlloc(buf *Buffer, size int) error {
+	if buf.limits == nil || buf.limits.MaxTotalAlloc <= 0 {
+		return nil
+	}
+	buf.readAlloc += size
+	if buf.readAlloc > buf.limits.MaxTotalAlloc {
+		return &LimitError{Limit: "MaxTotalAlloc", Max: buf.limits.MaxTotalAlloc, Actual: buf.readAlloc}
+	}
+	return nil
+}
+
+func clampInt(size uint64) int {
+	if size > uint64(^uint(0)>>1) {
+		return int(^uint(0) >> 1)
+	}
+	return int(size)
+}
+
+// readStringBytes read the bytes of a string which length is size
+func readStringBytes(buf *Buffer, size uint64) ([]byte, error) {
+	n, err := checkLength(buf, size, true)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Next(n)
+}
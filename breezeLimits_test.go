@@ -0,0 +1,76 @@
+package breeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeLimits(t *testing.T) {
+	// nested arrays
+	nested := NewBuffer(64)
+	for i := 0; i < 20; i++ {
+		nested.WriteByte(ArrayType)
+		nested.WriteVarInt(1)
+	}
+	WriteString(nested, "leaf", true)
+	buf := CreateBuffer(nested.Bytes())
+	if _, err := ReadValue(buf, nil); err != nil {
+		t.Errorf("read nested arrays without limits fail. err:%v", err)
+	}
+	buf = CreateBuffer(nested.Bytes())
+	buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 10})
+	checkLimitError(t, buf, nil, "MaxDepth")
+	if buf.readDepth != 0 {
+		t.Errorf("read depth should be restored after error. depth:%d", buf.readDepth)
+	}
+
+	// nested messages
+	msgBuf := NewBuffer(256)
+	WriteValue(msgBuf, getTestMsg())
+	buf = CreateBuffer(msgBuf.Bytes())
+	buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 1})
+	checkLimitError(t, buf, &TestMsg{}, "MaxDepth")
+	buf = CreateBuffer(msgBuf.Bytes())
+	buf.SetDecodeLimits(&DecodeLimits{MaxDepth: 4})
+	if _, err := ReadValue(buf, &TestMsg{}); err != nil {
+		t.Errorf("read message with max depth 4 fail. err:%v", err)
+	}
+
+	// total allocation
+	buf = CreateBuffer(msgBuf.Bytes())
+	buf.SetDecodeLimits(&DecodeLimits{MaxTotalAlloc: 100})
+	checkLimitError(t, buf, &TestMsg{}, "MaxTotalAlloc")
+	buf = CreateBuffer(msgBuf.Bytes())
+	buf.SetDecodeLimits(&DecodeLimits{MaxTotalAlloc: 10000})
+	if _, err := ReadValue(buf, &TestMsg{}); err != nil {
+		t.Errorf("read message with max total alloc fail. err:%v", err)
+	}
+
+	// huge declared length of bytes and string
+	hugeBytes := []byte{BytesType, 0x7f, 0xff, 0xff, 0xff, 1, 2}
+	hugeString := []byte{StringType, 0xff, 0xff, 0xff, 0xff, 0x0f, 'a'}
+	for _, data := range [][]byte{hugeBytes, hugeString} {
+		if _, err := ReadValue(CreateBuffer(data), nil); err != ErrNotEnough {
+			t.Errorf("huge length should return ErrNotEnough. err:%v", err)
+		}
+	}
+	buf = CreateBuffer(hugeBytes)
+	buf.SetDecodeLimits(&DecodeLimits{MaxBytesLength: 1024})
+	checkLimitError(t, buf, nil, "MaxBytesLength")
+	buf = CreateBuffer(hugeString)
+	buf.SetDecodeLimits(&DecodeLimits{MaxStringLength: 1024})
+	checkLimitError(t, buf, nil, "MaxStringLength")
+
+	// huge declared size of collection
+	hugeArray := []byte{ArrayType, 0xa0, 0x8d, 0x06, 1, 2}
+	if _, err := ReadValue(CreateBuffer(hugeArray), reflect.TypeOf([]string{})); err != ErrNotEnough {
+		t.Errorf("huge collection size should return ErrNotEnough. err:%v", err)
+	}
+}
+
+func checkLimitError(t *testing.T, buf *Buffer, v interface{}, limit string) {
+	_, err := ReadValue(buf, v)
+	if le, ok := err.(*LimitError); !ok || le.Limit != limit {
+		t.Errorf("should return LimitError of %s. err:%v", limit, err)
+	}
+}
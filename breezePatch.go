@@ -0,0 +1,242 @@
+package breeze
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+/*
+PatchField replace the field at path of an encoded message with value, and return the new encoding. the field is inserted
+after the other fields if it is not found, and removed if value is nil. the path is a field path of Extract without map or
+array selectors, such as subMsg.myInt or 5.2, the messages in path must exist.
+
+The other fields are copied without decoding, and the lengths of the enclosing messages are recomputed. the message type
+refs after the patched field are rewritten by name if the old value or the new value puts message types into the Context,
+so the refs are still valid in the new encoding. otherwise the bytes after the patched field are copied verbatim.
+*/
+func PatchField(data []byte, path string, value interface{}) ([]byte, error) {
+	steps, err := parseExtractPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("breeze: empty patch path")
+	}
+	for _, step := range steps {
+		if !step.isField {
+			return nil, errors.New("breeze: only message fields can be patched, path " + strconv.Quote(path))
+		}
+	}
+	src := CreateBuffer(data)
+	tp, name, err := readType(src)
+	if err != nil {
+		return nil, err
+	}
+	if tp != MessageType {
+		return nil, newTypeMismatchError(src, "message", TypeName(tp))
+	}
+	p := &patcher{src: src, dst: NewBuffer(len(data) + 64), path: path, value: value}
+	p.dst.Write(data[:src.GetRPos()])
+	if err = p.patchMessage(name, steps); err != nil {
+		return nil, err
+	}
+	if src.Remain() > 0 { // the values after the message
+		if !p.diverged {
+			p.dst.Write(data[src.GetRPos():])
+		}
+		for p.diverged && src.Remain() > 0 {
+			tp, name, err := readType(src)
+			if err == nil {
+				err = p.copier().copyValue(tp, name)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p.dst.Bytes(), nil
+}
+
+// patcher copy the message from src to dst and patch the field. the message type refs in src and dst are the same
+// until they are diverged by the patched value, the bytes are copied verbatim before that.
+type patcher struct {
+	src      *Buffer
+	dst      *Buffer
+	path     string
+	value    interface{}
+	diverged bool
+}
+
+// patchMessage copy the message body and patch the field at steps, the message type has been read and copied
+func (p *patcher) patchMessage(name string, steps []extractStep) (err error) {
+	src, dst := p.src, p.dst
+	total, err := src.ReadInt()
+	if err != nil {
+		return err
+	}
+	if total < 0 || total > src.Remain() {
+		return ErrNotEnough
+	}
+	if err = enterRead(src); err != nil {
+		return err
+	}
+	endPos := src.GetRPos() + total
+	wpos := src.wpos
+	src.wpos = endPos // fields can not be read beyond the message
+	defer func() {
+		src.wpos = wpos
+		leaveRead(src)
+		if isNotEnough(err) {
+			err = ErrWrongSize
+		}
+	}()
+	schema := src.GetContext().GetSchema(name)
+	if schema == nil {
+		schema = getRegisteredSchema(name)
+	}
+	pos := skipLength(dst)
+	copyFrom := src.GetRPos()
+	found := false
+	for src.GetRPos() < endPos {
+		fieldPos := src.GetRPos()
+		index, err := src.ReadVarInt()
+		if err != nil {
+			return err
+		}
+		if !steps[0].matchField(int(index), schema) {
+			if err = p.copyField(index); err != nil {
+				return err
+			}
+			continue
+		}
+		if !p.diverged {
+			dst.Write(src.buf[copyFrom:fieldPos])
+		}
+		if len(steps) == 1 {
+			p.syncContext()
+			if !found && p.value != nil { // the duplicated fields are removed
+				if err = p.writeField(int(index)); err != nil {
+					return err
+				}
+			}
+			if err = p.skipOldValue(); err != nil {
+				return err
+			}
+		} else {
+			typePos := src.GetRPos()
+			tp, name, err := readType(src)
+			if err != nil {
+				return err
+			}
+			if tp != MessageType {
+				return newTypeMismatchError(src, "message", TypeName(tp))
+			}
+			dst.WriteVarInt(index)
+			if p.diverged {
+				p.copier().writeType(tp, name)
+			} else {
+				dst.Write(src.buf[typePos:src.GetRPos()])
+			}
+			if err = p.patchMessage(name, steps[1:]); err != nil {
+				return err
+			}
+		}
+		found = true
+		copyFrom = src.GetRPos()
+	}
+	if !p.diverged {
+		dst.Write(src.buf[copyFrom:endPos])
+	}
+	if !found {
+		if len(steps) > 1 {
+			return errors.New("breeze: patch field not found, path " + strconv.Quote(p.path))
+		}
+		if p.value != nil {
+			index, err := p.fieldIndex(steps[0], schema)
+			if err != nil {
+				return err
+			}
+			p.syncContext()
+			if err = p.writeField(index); err != nil {
+				return err
+			}
+		}
+	}
+	writeLength(dst, pos)
+	return nil
+}
+
+// syncContext put the message types in src into dst if the refs are not diverged, so the refs in dst are the same as src
+func (p *patcher) syncContext() {
+	if p.diverged {
+		return
+	}
+	ctx, dstCtx := p.src.GetContext(), p.dst.GetContext()
+	for i := dstCtx.messageTypeRefCount + 1; i <= ctx.messageTypeRefCount; i++ {
+		dstCtx.putMessageType(ctx.getMessageTypeName(i))
+	}
+}
+
+// writeField write the new value with field index, the Context of dst has been synchronized
+func (p *patcher) writeField(index int) error {
+	dstCtx := p.dst.GetContext()
+	count := dstCtx.messageTypeRefCount
+	p.dst.SetWriteSchema(len(p.src.GetContext().schemas) > 0) // write schemas if the source has schemas
+	p.dst.WriteVarInt(uint64(index))
+	if err := WriteValue(p.dst, p.value); err != nil {
+		return err
+	}
+	if dstCtx.messageTypeRefCount != count {
+		p.diverge()
+	}
+	return nil
+}
+
+// skipOldValue skip the value of patched field. the refs are diverged if the value has message types
+func (p *patcher) skipOldValue() error {
+	count := p.src.GetContext().messageTypeRefCount
+	if err := SkipValue(p.src); err != nil {
+		return err
+	}
+	if p.src.GetContext().messageTypeRefCount != count {
+		p.diverge()
+	}
+	return nil
+}
+
+// copyField copy a field which is not patched, the index has been read. the field is copied verbatim later if the refs are not diverged
+func (p *patcher) copyField(index uint64) error {
+	if !p.diverged {
+		return SkipValue(p.src)
+	}
+	tp, name, err := readType(p.src)
+	if err != nil {
+		return err
+	}
+	p.dst.WriteVarInt(index)
+	return p.copier().copyValue(tp, name)
+}
+
+// diverge make the refs after current position rewritten by name
+func (p *patcher) diverge() {
+	p.diverged = true
+	p.dst.SetWriteSchema(len(p.src.GetContext().schemas) > 0)
+}
+
+func (p *patcher) copier() *rawCopier {
+	return &rawCopier{src: p.src, dst: p.dst, schemas: p.src.GetContext().schemas}
+}
+
+// fieldIndex get the index of a field which is not in message, the schema is needed if the field is a name
+func (p *patcher) fieldIndex(step extractStep, schema *Schema) (int, error) {
+	if index, err := strconv.Atoi(step.name); err == nil && index >= 0 {
+		return index, nil
+	}
+	if schema != nil {
+		if field := schema.GetFieldByName(step.name); field != nil {
+			return field.Index, nil
+		}
+	}
+	return 0, errors.New("breeze: unknown patch field " + strconv.Quote(step.name) + ", path " + strconv.Quote(p.path))
+}
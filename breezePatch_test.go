@@ -0,0 +1,129 @@
+package breeze
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPatchField(t *testing.T) {
+	// the field names are got from registered schemas
+	RegisterMessage(&TestMsg{})
+	RegisterMessage(&TestSubMsg{})
+	defer func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		delete(messageTypes, testMsgBreezeSchema.Name)
+		delete(messageTypes, testSubMsgBreezeSchema.Name)
+	}()
+	write := func(msg *TestMsg) []byte {
+		buf := NewBuffer(1024)
+		WriteValue(buf, msg)
+		return buf.Bytes()
+	}
+	data := write(getSingleEntryTestMsg())
+
+	// replace, remove and insert
+	tests := []struct {
+		path   string
+		value  interface{}
+		modify func(msg *TestMsg)
+	}{
+		{"myInt", int32(99), func(msg *TestMsg) { msg.MyInt = 99 }},
+		{"subMsg.myString", "patched", func(msg *TestMsg) { msg.SubMsg.MyString = "patched" }},
+		{"5.7", []byte("patched"), func(msg *TestMsg) { msg.SubMsg.MyBytes = []byte("patched") }},
+		{"subMsg.myInt", nil, func(msg *TestMsg) { msg.SubMsg.MyInt = 0 }},
+		{"myString", nil, func(msg *TestMsg) { msg.MyString = "" }},
+	}
+	for _, test := range tests {
+		patched, err := PatchField(data, test.path, test.value)
+		if err != nil {
+			t.Fatalf("patch %s fail. err:%v", test.path, err)
+		}
+		expect := getSingleEntryTestMsg()
+		test.modify(expect)
+		if !bytes.Equal(patched, write(expect)) {
+			t.Errorf("wrong bytes of patch %s. expect:%x, real:%x", test.path, write(expect), patched)
+		}
+	}
+
+	// insert after the other fields
+	removed, _ := PatchField(data, "myString", nil)
+	inserted, err := PatchField(removed, "myString", "inserted")
+	result := &TestMsg{}
+	if _, err = ReadValue(CreateBuffer(inserted), result); err != nil || result.MyString != "inserted" {
+		t.Errorf("field should be inserted. err:%v, value:%s", err, result.MyString)
+	}
+	if inserted, err = PatchField(data, "subMsg.100", int64(1)); err != nil {
+		t.Fatalf("patch unknown field fail. err:%v", err)
+	}
+	result = &TestMsg{}
+	ReadValue(CreateBuffer(inserted), result)
+	if v, err := result.SubMsg.GetUnknownField(100).Decode(nil); err != nil || v != int64(1) {
+		t.Errorf("unknown field should be inserted. err:%v, value:%v", err, v)
+	}
+
+	// the message type refs after patched field are rewritten
+	other := &GenericMessage{Name: "motan.Other"}
+	other.PutField(1, "other")
+	newSub := getTestSubMsgByInt(5)
+	for _, test := range []struct {
+		path   string
+		value  interface{}
+		modify func(msg *TestMsg)
+	}{
+		{"myMap", nil, func(msg *TestMsg) { msg.MyMap = nil }}, // the first TestSubMsg is removed
+		{"myMap", map[string]*TestSubMsg{"m2": newSub}, func(msg *TestMsg) { msg.MyMap = map[string]*TestSubMsg{"m2": newSub} }},
+		{"myInt", other, nil}, // a new message type before TestSubMsg
+	} {
+		for _, writeSchema := range []bool{false, true} {
+			buf := NewBuffer(1024)
+			buf.SetWriteSchema(writeSchema)
+			WriteValue(buf, getTestMsg())
+			WriteValue(buf, getTestSubMsg()) // a ref message after the patched message
+			patched, err := PatchField(buf.Bytes(), test.path, test.value)
+			if err != nil {
+				t.Fatalf("patch %s fail. err:%v", test.path, err)
+			}
+			expect := getTestMsg()
+			if test.modify != nil {
+				test.modify(expect)
+			}
+			rbuf := CreateBuffer(patched)
+			var v interface{}
+			if test.modify == nil {
+				v, err = ReadValue(rbuf, &GenericMessage{Name: testMsgBreezeSchema.Name})
+			} else {
+				v, err = ReadValue(rbuf, nil)
+			}
+			if err != nil {
+				t.Fatalf("read patched message of %s fail. err:%v", test.path, err)
+			}
+			if test.modify == nil {
+				msg := v.(*GenericMessage)
+				if !reflect.DeepEqual(msg.GetFieldByIndex(1), other) || !reflect.DeepEqual(msg.GetFieldByIndex(4), []interface{}{expect.MyArray[0]}) {
+					t.Errorf("wrong patched message of %s: %v", test.path, msg)
+				}
+			} else if !reflect.DeepEqual(v, expect) {
+				t.Errorf("wrong patched message of %s. expect:%v, real:%v", test.path, expect, v)
+			}
+			if v, err = ReadValue(rbuf, nil); err != nil || !reflect.DeepEqual(v, getTestSubMsg()) || rbuf.Remain() != 0 {
+				t.Errorf("wrong message after patched message of %s. err:%v, value:%v", test.path, err, v)
+			}
+			if writeSchema && rbuf.GetContext().GetSchema(testSubMsgBreezeSchema.Name) == nil {
+				t.Errorf("schema should be kept in patched message of %s", test.path)
+			}
+		}
+	}
+
+	// invalid patches
+	noSub := write(getTestMsg())
+	for _, path := range []string{"", "myMap[m1].myInt", "myArray.myInt", "subMsg.myInt", "noSuchField", "myInt..a"} {
+		if _, err := PatchField(noSub, path, int32(1)); err == nil {
+			t.Errorf("patch %s should fail", path)
+		}
+	}
+	if _, err := PatchField(noSub[:len(noSub)-1], "myInt", int32(1)); err == nil {
+		t.Errorf("patch truncated message should fail")
+	}
+}
@@ -0,0 +1,294 @@
+package breeze
+
+import (
+	"encoding/binary"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+/*
+RawValue is an encoded breeze value with type, it can be used to forward values without decoding them, for example:
+
+	var raw breeze.RawValue
+	_, err := breeze.ReadValue(buf, &raw) // or breeze.ReadRawValue(buf) in ReadMessageField
+	...
+	breeze.WriteValue(outBuf, &raw) // or breeze.WriteField(outBuf, index, &raw)
+
+the fields, map values and array elements of type RawValue or *RawValue are read and written as raw values too.
+the message type refs in raw values depend on the Context which reads or writes them, so the message types are kept by
+name when a raw value is read, and are written by the Context of the target buffer. the other bytes are written verbatim.
+*/
+type RawValue struct {
+	data       []byte // the encoded value, the message type refs are relative to a new Context
+	order      binary.ByteOrder
+	hasMessage bool
+	schemas    map[string]*Schema // the schemas of message types found in the source Context
+}
+
+// ReadRawValue read a value with type as RawValue
+func ReadRawValue(buf *Buffer) (*RawValue, error) {
+	tp, name, err := readType(buf)
+	if err != nil {
+		return nil, err
+	}
+	raw := &RawValue{}
+	return raw, raw.read(buf, tp, name)
+}
+
+// Bytes get the encoded bytes of the value. the message types are encoded as they are written into a new Buffer
+func (r *RawValue) Bytes() []byte {
+	return r.data
+}
+
+// Decode read the raw value as ReadValue does
+func (r *RawValue) Decode(v interface{}) (interface{}, error) {
+	return ReadValue(r.newReader(), v)
+}
+
+func (r *RawValue) newReader() *Buffer {
+	order := r.order
+	if order == nil {
+		order = binary.BigEndian
+	}
+	buf := CreateBufferWithOrder(r.data, order)
+	for _, schema := range r.schemas {
+		buf.GetContext().PutSchema(schema)
+	}
+	return buf
+}
+
+// read a value which breeze type is tp(and message name is name), the type has been read
+func (r *RawValue) read(buf *Buffer, tp byte, name string) error {
+	start := buf.GetRPos()
+	out := NewBufferWithOrder(64, buf.order)
+	c := &rawCopier{src: buf, dst: out}
+	if err := c.copyValue(tp, name); err != nil {
+		return err
+	}
+	r.data = out.Bytes()
+	r.order = buf.order
+	r.hasMessage = len(c.names) > 0
+	if !r.hasMessage { // keep the original bytes
+		r.data = append(r.data[:1], buf.buf[start:buf.GetRPos()]...)
+	}
+	r.schemas = nil
+	for _, name := range c.names {
+		if schema := buf.GetContext().GetSchema(name); schema != nil {
+			if r.schemas == nil {
+				r.schemas = make(map[string]*Schema, len(c.names))
+			}
+			r.schemas[name] = schema
+		}
+	}
+	return nil
+}
+
+// writeTo write the raw value with type into buffer
+func (r *RawValue) writeTo(buf *Buffer) error {
+	if len(r.data) == 0 {
+		return errors.New("breeze: empty raw value")
+	}
+	if !r.hasMessage && r.order == buf.order {
+		buf.Write(r.data)
+		return nil
+	}
+	c := &rawCopier{src: r.newReader(), dst: buf, schemas: r.schemas}
+	tp, name, err := readType(c.src)
+	if err != nil {
+		return err
+	}
+	return c.copyValue(tp, name)
+}
+
+// rawCopier copy values from src to dst. the message types are written by the Context of dst,
+// and the fixed length numbers are converted to the byte order of dst.
+type rawCopier struct {
+	src     *Buffer
+	dst     *Buffer
+	schemas map[string]*Schema
+	names   []string // the message names have been copied
+}
+
+// copyValue copy a value which breeze type is tp(and message name is name) with type, the type has been read from src
+func (c *rawCopier) copyValue(tp byte, name string) error {
+	c.writeType(tp, name)
+	return c.copyBody(tp, name)
+}
+
+func (c *rawCopier) writeType(tp byte, name string) {
+	if tp == MessageType {
+		writeMessageType(c.dst, name, c.schemas[name])
+		for _, n := range c.names {
+			if n == name {
+				return
+			}
+		}
+		c.names = append(c.names, name)
+		return
+	}
+	c.dst.WriteByte(tp)
+}
+
+// copyBody copy a value without type
+func (c *rawCopier) copyBody(tp byte, name string) error {
+	src, dst := c.src, c.dst
+	switch {
+	case tp <= DirectStringMaxType:
+		return c.copyBytes(uint64(tp))
+	case tp == StringType:
+		size, err := src.ReadVarInt()
+		if err != nil {
+			return err
+		}
+		dst.WriteVarInt(size)
+		return c.copyBytes(size)
+	case tp >= DirectInt32MinType && tp <= DirectInt32MaxType, tp >= DirectInt64MinType && tp <= DirectInt64MaxType:
+		return nil
+	case tp == Int32Type || tp == Int64Type:
+		i, err := src.ReadVarInt()
+		dst.WriteVarInt(i)
+		return err
+	}
+	switch tp {
+	case NullType, TrueType, FalseType:
+		return nil
+	case ByteType:
+		b, err := src.ReadByte()
+		dst.WriteByte(b)
+		return err
+	case Int16Type:
+		i, err := src.ReadUint16()
+		dst.WriteUint16(i)
+		return err
+	case Float32Type:
+		i, err := src.ReadUint32()
+		dst.WriteUint32(i)
+		return err
+	case Float64Type:
+		i, err := src.ReadUint64()
+		dst.WriteUint64(i)
+		return err
+	case BytesType:
+		size, err := src.ReadUint32()
+		if err != nil {
+			return err
+		}
+		dst.WriteUint32(size)
+		return c.copyBytes(uint64(size))
+	case MessageType:
+		pos := skipLength(dst)
+		err := ReadMessageField(src, func(src *Buffer, index int) error {
+			dst.WriteVarInt(uint64(index))
+			tp, name, err := readType(src)
+			if err != nil {
+				return err
+			}
+			return c.copyValue(tp, name)
+		})
+		writeLength(dst, pos)
+		return err
+	case MapType, PackedMapType, ArrayType, PackedArrayType:
+		return c.copyCollection(tp)
+	}
+	return newUnknownTypeError(src, "value", tp)
+}
+
+func (c *rawCopier) copyCollection(tp byte) (err error) {
+	src, dst := c.src, c.dst
+	total, err := src.ReadVarInt()
+	if err != nil {
+		return err
+	}
+	if total > uint64(src.Remain()) { // every element takes one byte at least
+		return ErrNotEnough
+	}
+	dst.WriteVarInt(total)
+	if total == 0 {
+		return nil
+	}
+	if err = enterRead(src); err != nil {
+		return err
+	}
+	defer leaveRead(src)
+	size := int(total)
+	isMap := tp == MapType || tp == PackedMapType
+	if isMap {
+		size *= 2
+	}
+	if tp == MapType || tp == ArrayType {
+		for i := 0; i < size; i++ {
+			etp, name, err := readType(src)
+			if err != nil {
+				return err
+			}
+			if err = c.copyValue(etp, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ktp, kn, err := readType(src) // key type of map or element type of array
+	if err != nil {
+		return err
+	}
+	c.writeType(ktp, kn)
+	vtp, vn := ktp, kn
+	if isMap {
+		if vtp, vn, err = readType(src); err != nil { // value type
+			return err
+		}
+		c.writeType(vtp, vn)
+	}
+	for i := 0; i < size; i++ {
+		tp, name := ktp, kn
+		if isMap && i%2 == 1 {
+			tp, name = vtp, vn
+		}
+		etp, err := packedElemType(src, tp)
+		if err != nil {
+			return err
+		}
+		if etp == TrueType || etp == FalseType {
+			dst.WriteByte(etp)
+		} else if err = c.copyBody(etp, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *rawCopier) copyBytes(size uint64) error {
+	if size > uint64(c.src.Remain()) {
+		return ErrNotEnough
+	}
+	bytes, err := c.src.Next(int(size))
+	c.dst.Write(bytes)
+	return err
+}
+
+var rawValueType = reflect.TypeOf(RawValue{})
+
+// buildRawValueCodec build the codec of RawValue. raw values can not be written without type, so they are not packed in maps and arrays
+func buildRawValueCodec(c *codec) {
+	c.encode = func(buf *Buffer, rv reflect.Value, withType bool) error {
+		if !withType {
+			return errors.New("breeze: raw value can not be written without type")
+		}
+		if rv.CanAddr() {
+			return rv.Addr().Interface().(*RawValue).writeTo(buf)
+		}
+		raw := rv.Interface().(RawValue)
+		return raw.writeTo(buf)
+	}
+	c.writeType = func(buf *Buffer, rv reflect.Value) error {
+		return errors.New("breeze: raw value can not be written without type")
+	}
+	c.decode = func(buf *Buffer, tp byte, name string) (interface{}, error) {
+		raw := RawValue{}
+		if err := raw.read(buf, tp, name); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+}
@@ -0,0 +1,126 @@
+package breeze
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type testRawMsg struct {
+	_         struct{}  `breeze:"name=motan.TestMsg"`
+	MyInt     int32     `breeze:"1"`
+	MyMap     RawValue  `breeze:"3"`
+	MyArray   *RawValue `breeze:"4"`
+	EnumArray RawValue  `breeze:"7"`
+}
+
+func TestRawValue(t *testing.T) {
+	// values without message are written verbatim
+	values := []interface{}{true, "string", byte(3), []byte("bytes"), int16(-12), int32(1234), int64(-456789), float32(1.23), 4.56,
+		[]string{"a", "b"}, []bool{true, false}, map[string]int64{"k": 1}, map[interface{}]interface{}{"a": int32(1), int64(2): []interface{}{"x", true, nil}}}
+	for _, v := range values {
+		buf := NewBuffer(64)
+		WriteValue(buf, v)
+		var raw RawValue
+		if _, err := ReadValue(CreateBuffer(buf.Bytes()), &raw); err != nil || !bytes.Equal(raw.Bytes(), buf.Bytes()) {
+			t.Fatalf("wrong raw value. err:%v, expect:%x, real:%x", err, buf.Bytes(), raw.Bytes())
+		}
+		out := NewBuffer(64)
+		if err := WriteValue(out, &raw); err != nil || !bytes.Equal(out.Bytes(), buf.Bytes()) {
+			t.Errorf("raw value should be written verbatim. err:%v, expect:%x, real:%x", err, buf.Bytes(), out.Bytes())
+		}
+		expect, _ := ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(v))
+		if result, err := raw.Decode(reflect.TypeOf(v)); err != nil || !reflect.DeepEqual(expect, result) {
+			t.Errorf("wrong decoded value. err:%v, expect:%v, real:%v", err, expect, result)
+		}
+	}
+
+	// message types are rewritten by the context of target buffer
+	buf := NewBuffer(1024)
+	WriteValue(buf, getTestSubMsg())
+	WriteValue(buf, getTestMsg()) // TestSubMsg is a ref in TestMsg
+	rbuf := CreateBuffer(buf.Bytes())
+	if err := SkipValue(rbuf); err != nil {
+		t.Fatalf("skip fail. err:%v", err)
+	}
+	raw, err := ReadRawValue(rbuf)
+	if err != nil {
+		t.Fatalf("read raw value fail. err:%v", err)
+	}
+	for _, prefix := range []interface{}{nil, getTestSubMsg(), MyEnum(1)} {
+		out := NewBuffer(1024)
+		if prefix != nil { // the refs of target context are different from the source
+			WriteValue(out, prefix)
+		}
+		WriteValue(out, []interface{}{raw, raw})
+		in := CreateBuffer(out.Bytes())
+		if prefix != nil {
+			SkipValue(in)
+		}
+		var result []*TestMsg
+		if _, err = ReadValue(in, &result); err != nil || len(result) != 2 || !reflect.DeepEqual(result[1], getTestMsg()) {
+			t.Errorf("wrong message from raw value. prefix:%v, err:%v", prefix, err)
+		}
+	}
+
+	// fields of struct
+	buf = NewBuffer(1024)
+	buf.SetWriteSchema(true)
+	WriteValue(buf, getTestMsg())
+	msg := &testRawMsg{}
+	if _, err = ReadValue(CreateBuffer(buf.Bytes()), msg); err != nil || msg.MyInt != 12 || msg.MyArray == nil || len(msg.EnumArray.Bytes()) == 0 {
+		t.Fatalf("wrong raw fields. err:%v, value:%+v", err, msg)
+	}
+	out := NewBuffer(1024)
+	out.SetWriteSchema(true)
+	if err = WriteValue(out, msg); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	in := CreateBuffer(out.Bytes())
+	testMsg := &TestMsg{}
+	expect := getTestMsg()
+	expect.MyString = ""
+	expect.SubMsg = nil
+	expect.MyEnum = nil
+	if _, err = ReadValue(in, testMsg); err != nil || !reflect.DeepEqual(expect, testMsg) {
+		t.Errorf("wrong message from raw fields. err:%v, expect:%+v, real:%+v", err, expect, testMsg)
+	}
+	if msg.EnumArray.schemas["motan.MyEnum"] == nil || in.GetContext().GetSchema("motan.MyEnum") == nil {
+		t.Errorf("schema of message in raw value should be written")
+	}
+
+	// raw values in maps and arrays are not packed
+	m := map[string][]*RawValue{"a": {raw, &msg.MyMap}}
+	out = NewBuffer(1024)
+	if err = WriteValue(out, m); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	var rm map[string][]interface{}
+	if _, err = ReadValue(CreateBuffer(out.Bytes()), &rm); err != nil || len(rm["a"]) != 2 {
+		t.Errorf("wrong raw values in map. err:%v, value:%v", err, rm)
+	} else if g, ok := rm["a"][0].(*GenericMessage); !ok || g.GetName() != "motan.TestMsg" {
+		t.Errorf("wrong raw message in map: %v", rm["a"][0])
+	}
+
+	// byte order is converted
+	buf = NewBufferWithOrder(1024, binary.LittleEndian)
+	WriteValue(buf, getTestMsg())
+	raw, err = ReadRawValue(CreateBufferWithOrder(buf.Bytes(), binary.LittleEndian))
+	if err != nil {
+		t.Fatalf("read raw value fail. err:%v", err)
+	}
+	out = NewBuffer(1024)
+	WriteValue(out, raw)
+	testMsg = &TestMsg{}
+	if _, err = ReadValue(CreateBuffer(out.Bytes()), testMsg); err != nil || !reflect.DeepEqual(getTestMsg(), testMsg) {
+		t.Errorf("wrong message from little endian raw value. err:%v, value:%+v", err, testMsg)
+	}
+
+	if err = WriteValue(NewBuffer(16), &RawValue{}); err == nil {
+		t.Errorf("empty raw value should not be written")
+	}
+	if _, err = ReadRawValue(CreateBuffer([]byte{MapType, 2, 0x01, 'a'})); err == nil {
+		t.Errorf("read malformed raw value should fail")
+	}
+}
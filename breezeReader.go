@@ -4,7 +4,6 @@ import (
 	"github.com/pkg/errors"
 	"math"
 	"reflect"
-	"strconv"
 )
 
 // default value of breeze reader
@@ -18,9 +17,21 @@ type ReadFieldsFunc func(buf *Buffer, index int) error
 // ReadElemFunc read one element of map or array
 type ReadElemFunc func(buf *Buffer) error
 
-// ReadBool read a bool value into the bool pointer
+// ReadBool read a bool value into the bool pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadBool(buf *Buffer, b *bool) (err error) {
-	*b, err = ReadBoolWithoutType(buf)
+	tp, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	if tp == TrueType || tp == FalseType {
+		*b = tp == TrueType
+		return nil
+	}
+	sv, err := readScalarOf(buf, tp, "bool")
+	if err != nil {
+		return err
+	}
+	*b, err = sv.toBool(buf, "bool")
 	return err
 }
 
@@ -35,115 +46,85 @@ func ReadBoolWithoutType(buf *Buffer) (bool, error) {
 	} else if tp == FalseType {
 		return false, nil
 	} else {
-		return false, errors.New("BreezeRead: wrong type expect bool, real " + strconv.Itoa(int(tp)))
+		return false, newTypeMismatchError(buf, "bool", TypeName(tp))
 	}
 }
 
-// ReadString read a string value into a pointer
+// ReadString read a string value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadString(buf *Buffer, s *string) (err error) {
 	tp, err := buf.ReadByte()
 	if err != nil {
 		return err
 	}
 	if tp <= DirectStringMaxType {
-		bytes, err := buf.Next(int(tp))
+		bytes, err := readStringBytes(buf, uint64(tp))
 		if err != nil {
 			return err
 		}
 		*s = string(bytes)
 		return nil
 	}
-	switch int(tp) {
-	case StringType:
+	if tp == StringType {
 		*s, err = ReadStringWithoutType(buf)
-	case Int32Type:
-		i32, err := ReadInt32WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*s = strconv.Itoa(int(i32))
-	case Int64Type:
-		i64, err := ReadInt64WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*s = strconv.FormatInt(i64, 10)
-	case Int16Type:
-		i16, err := ReadInt16WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*s = strconv.Itoa(int(i16))
-	case Float32Type:
-		f32, err := ReadFloat32WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*s = strconv.FormatFloat(float64(f32), 'f', -1, 32)
-	case Float64Type:
-		f64, err := ReadFloat64WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*s = strconv.FormatFloat(f64, 'f', -1, 64)
-	default:
-		err = errors.New("Breeze: not convert to string, type " + strconv.Itoa(int(tp)))
+		return err
+	}
+	sv, err := readScalarOf(buf, tp, "string")
+	if err != nil {
+		return err
 	}
+	*s, err = sv.toString(buf, "string")
 	return err
 }
 
-// ReadByte read a byte value into a pointer
+// ReadByte read a byte value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadByte(buf *Buffer, b *byte) error {
-	err := checkType(buf, ByteType)
+	tp, err := buf.ReadByte()
 	if err != nil {
 		return err
 	}
-	*b, err = buf.ReadByte()
+	if tp == ByteType {
+		*b, err = buf.ReadByte()
+		return err
+	}
+	sv, err := readScalarOf(buf, tp, "byte")
+	if err != nil {
+		return err
+	}
+	i, err := sv.toInt(buf, "byte", 0, math.MaxUint8)
+	*b = byte(i)
 	return err
 }
 
-// ReadBytes read a byte slice value into a pointer
+// ReadBytes read a byte slice value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadBytes(buf *Buffer, bytes *[]byte) error {
-	err := checkType(buf, BytesType)
+	tp, err := buf.ReadByte()
 	if err != nil {
 		return err
 	}
-	*bytes, err = ReadBytesWithoutType(buf)
+	if tp == BytesType {
+		*bytes, err = ReadBytesWithoutType(buf)
+		return err
+	}
+	sv, err := readScalarOf(buf, tp, "[]byte")
+	if err != nil {
+		return err
+	}
+	*bytes, err = sv.toBytes(buf, "[]byte")
 	return err
 }
 
-// ReadInt16 read a int16 value into a pointer
+// ReadInt16 read a int16 value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadInt16(buf *Buffer, i *int16) error {
 	tp, err := buf.ReadByte()
 	if err != nil {
 		return err
 	}
-	switch int(tp) {
-	case Int16Type:
+	if tp == Int16Type {
 		*i, err = ReadInt16WithoutType(buf)
-	case StringType:
-		s, err := ReadStringWithoutType(buf)
-		if err != nil {
-			return err
-		}
-		si, err := strconv.Atoi(s)
-		*i = int16(si)
 		return err
-	case Int32Type:
-		i32, err := ReadInt32WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i = int16(i32)
-	case Int64Type:
-		i64, err := ReadInt64WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i = int16(i64)
-	default:
-		err = errors.New("Breeze: not convert to int16, type " + strconv.Itoa(int(tp)))
 	}
+	i64, err := readInt(buf, tp, "int16", math.MinInt16, math.MaxInt16)
+	*i = int16(i64)
 	return err
 }
 
@@ -155,7 +136,7 @@ func ReadInt(buf *Buffer, i *int) error {
 	return err
 }
 
-// ReadInt32 read a int32 value into a pointer
+// ReadInt32 read a int32 value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadInt32(buf *Buffer, i *int32) error {
 	tp, err := buf.ReadByte()
 	if err != nil {
@@ -165,36 +146,16 @@ func ReadInt32(buf *Buffer, i *int32) error {
 		*i = int32(tp) - Int32Zero
 		return nil
 	}
-	switch int(tp) {
-	case Int32Type:
+	if tp == Int32Type {
 		*i, err = ReadInt32WithoutType(buf)
-	case StringType:
-		s, err := ReadStringWithoutType(buf)
-		if err != nil {
-			return err
-		}
-		si, err := strconv.Atoi(s)
-		*i = int32(si)
 		return err
-	case Int64Type:
-		i64, err := ReadInt64WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i = int32(i64)
-	case Int16Type:
-		i16, err := ReadInt16WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i = int32(i16)
-	default:
-		err = errors.New("Breeze: not convert to int32, type " + strconv.Itoa(int(tp)))
 	}
+	i64, err := readInt(buf, tp, "int32", math.MinInt32, math.MaxInt32)
+	*i = int32(i64)
 	return err
 }
 
-// ReadInt64 read a int64 value into a pointer
+// ReadInt64 read a int64 value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadInt64(buf *Buffer, i *int64) error {
 	tp, err := buf.ReadByte()
 	if err != nil {
@@ -204,130 +165,313 @@ func ReadInt64(buf *Buffer, i *int64) error {
 		*i = int64(tp) - Int64Zero
 		return nil
 	}
-	switch int(tp) {
-	case Int64Type:
+	if tp == Int64Type {
 		*i, err = ReadInt64WithoutType(buf)
-	case StringType:
-		s, err := ReadStringWithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i, err = strconv.ParseInt(s, 10, 64)
 		return err
-	case Int32Type:
-		i32, err := ReadInt32WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i = int64(i32)
-	case Int16Type:
-		i16, err := ReadInt16WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*i = int64(i16)
-	default:
-		err = errors.New("Breeze: not convert to int64, type " + strconv.Itoa(int(tp)))
 	}
+	*i, err = readInt(buf, tp, "int64", math.MinInt64, math.MaxInt64)
 	return err
 }
 
-// ReadFloat32 read a float32 value into a pointer
+// ReadFloat32 read a float32 value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadFloat32(buf *Buffer, f *float32) error {
 	tp, err := buf.ReadByte()
 	if err != nil {
 		return err
 	}
-	switch int(tp) {
-	case Float32Type:
+	if tp == Float32Type {
 		*f, err = ReadFloat32WithoutType(buf)
-	case Float64Type:
-		f64, err := ReadFloat64WithoutType(buf)
-		if err != nil {
-			return err
-		}
-		*f = float32(f64)
-	case StringType:
-		s, err := ReadStringWithoutType(buf)
-		if err != nil {
-			return err
-		}
-		f64, err := strconv.ParseFloat(s, 64)
-		*f = float32(f64)
 		return err
-	default:
-		err = errors.New("Breeze: not convert to float32, type " + strconv.Itoa(int(tp)))
 	}
+	f64, err := readFloat(buf, tp, "float32", 32)
+	*f = float32(f64)
 	return err
 }
 
-// ReadFloat64 read a float64 value into a pointer
+// ReadFloat64 read a float64 value into a pointer. the value is coerced by the coercion table in breezeCoerce.go
 func ReadFloat64(buf *Buffer, f *float64) error {
 	tp, err := buf.ReadByte()
 	if err != nil {
 		return err
 	}
-	switch int(tp) {
-	case Float64Type:
+	if tp == Float64Type {
 		*f, err = ReadFloat64WithoutType(buf)
-	case Float32Type:
-		f32, err := ReadFloat32WithoutType(buf)
-		if err != nil {
-			return err
+		return err
+	}
+	*f, err = readFloat(buf, tp, "float64", 64)
+	return err
+}
+
+func readInt(buf *Buffer, tp byte, expected string, min int64, max int64) (int64, error) {
+	sv, err := readScalarOf(buf, tp, expected)
+	if err != nil {
+		return 0, err
+	}
+	return sv.toInt(buf, expected, min, max)
+}
+
+func readFloat(buf *Buffer, tp byte, expected string, bitSize int) (float64, error) {
+	sv, err := readScalarOf(buf, tp, expected)
+	if err != nil {
+		return 0, err
+	}
+	return sv.toFloat(buf, expected, bitSize)
+}
+
+// readNull read the type if it is NullType, otherwise the type is not read
+func readNull(buf *Buffer) (bool, error) {
+	tp, err := buf.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	if tp != NullType {
+		buf.SetRPos(buf.GetRPos() - 1)
+	}
+	return tp == NullType, nil
+}
+
+// ReadOptionalBool read a optional bool value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalBool(buf *Buffer, b **bool) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*b = nil
 		}
-		*f = float64(f32)
-	case StringType:
-		s, err := ReadStringWithoutType(buf)
-		if err != nil {
-			return err
+		return err
+	}
+	v := new(bool)
+	if err = ReadBool(buf, v); err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// ReadOptionalString read a optional string value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalString(buf *Buffer, s **string) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*s = nil
 		}
-		*f, err = strconv.ParseFloat(s, 64)
-		if err != nil {
-			return err
+		return err
+	}
+	v := new(string)
+	if err = ReadString(buf, v); err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// ReadOptionalByte read a optional byte value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalByte(buf *Buffer, b **byte) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*b = nil
 		}
-	default:
-		err = errors.New("Breeze: not convert to float64 type " + strconv.Itoa(int(tp)))
+		return err
 	}
-	return err
+	v := new(byte)
+	if err = ReadByte(buf, v); err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// ReadOptionalInt16 read a optional int16 value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalInt16(buf *Buffer, i **int16) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*i = nil
+		}
+		return err
+	}
+	v := new(int16)
+	if err = ReadInt16(buf, v); err != nil {
+		return err
+	}
+	*i = v
+	return nil
+}
+
+// ReadOptionalInt32 read a optional int32 value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalInt32(buf *Buffer, i **int32) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*i = nil
+		}
+		return err
+	}
+	v := new(int32)
+	if err = ReadInt32(buf, v); err != nil {
+		return err
+	}
+	*i = v
+	return nil
 }
 
-// ReadPackedSize read size of packed map or packed array
+// ReadOptionalInt64 read a optional int64 value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalInt64(buf *Buffer, i **int64) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*i = nil
+		}
+		return err
+	}
+	v := new(int64)
+	if err = ReadInt64(buf, v); err != nil {
+		return err
+	}
+	*i = v
+	return nil
+}
+
+// ReadOptionalFloat32 read a optional float32 value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalFloat32(buf *Buffer, f **float32) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*f = nil
+		}
+		return err
+	}
+	v := new(float32)
+	if err = ReadFloat32(buf, v); err != nil {
+		return err
+	}
+	*f = v
+	return nil
+}
+
+// ReadOptionalFloat64 read a optional float64 value into a pointer. the pointer is set to nil if the value is null
+func ReadOptionalFloat64(buf *Buffer, f **float64) error {
+	isNull, err := readNull(buf)
+	if isNull || err != nil {
+		if isNull {
+			*f = nil
+		}
+		return err
+	}
+	v := new(float64)
+	if err = ReadFloat64(buf, v); err != nil {
+		return err
+	}
+	*f = v
+	return nil
+}
+
+// ReadPackedSize read size of packed map or packed array. the type must be packed map or packed array in strict mode
 func ReadPackedSize(buf *Buffer, withType bool) (int, error) {
+	return readPackedSize(buf, withType, 0)
+}
+
+// ReadPackedMapSize read size of packed map. the type must be packed map in strict mode
+func ReadPackedMapSize(buf *Buffer, withType bool) (int, error) {
+	return readPackedSize(buf, withType, PackedMapType)
+}
+
+// ReadPackedArraySize read size of packed array. the type must be packed array in strict mode
+func ReadPackedArraySize(buf *Buffer, withType bool) (int, error) {
+	return readPackedSize(buf, withType, PackedArrayType)
+}
+
+// readPackedSize read size of packed collection. tp is PackedMapType or PackedArrayType to check in strict mode, 0 means both
+func readPackedSize(buf *Buffer, withType bool, tp byte) (int, error) {
 	if withType {
-		_, err := buf.ReadByte() //ignored
+		ctp, err := buf.ReadByte()
 		if err != nil {
 			return 0, err
 		}
+		expected, ok := "map or array", ctp == PackedMapType || ctp == PackedArrayType
+		if tp == PackedMapType {
+			expected, ok = "map", ctp == tp
+		} else if tp == PackedArrayType {
+			expected, ok = "array", ctp == tp
+		}
+		if buf.strict && !ok {
+			return 0, newTypeMismatchError(buf, expected, TypeName(ctp))
+		}
 	}
 	i, err := buf.ReadVarInt()
 	if err != nil {
 		return 0, err
 	}
-	size := int(i)
-	if size > MaxElemSize {
-		return 0, errors.New("collection elem size overflow. size:" + strconv.Itoa(size))
-	}
-	return size, nil
+	return checkElemSize(buf, i)
 }
 
 // ReadPacked read packed map or packed array without type
 func ReadPacked(buf *Buffer, size int, isMap bool, f ReadElemFunc) (err error) {
+	return readPacked(buf, size, isMap, nil, f)
+}
+
+// readPacked read packed map or packed array, and check the key type and value type(element type of array) by checkTypes
+func readPacked(buf *Buffer, size int, isMap bool, checkTypes func(ktp byte, vtp byte) error, f ReadElemFunc) (err error) {
 	if size <= 0 {
 		return nil
 	}
-	readType(buf) // skip key type
+	if err = enterRead(buf); err != nil {
+		return err
+	}
+	defer leaveRead(buf)
+	ktp, kn, err := readType(buf) // key type of map or element type of array
+	if err != nil {
+		return err
+	}
+	tp, name := ktp, kn
 	if isMap {
-		readType(buf) // skip value type
+		if tp, name, err = readType(buf); err != nil { // value type
+			return err
+		}
+	}
+	if checkTypes != nil {
+		if err = checkTypes(ktp, tp); err != nil {
+			return err
+		}
 	}
 	for i := 0; i < size; i++ {
+		pos := buf.GetRPos()
+		if tp == MessageType {
+			setReadingMessage(buf, name, nil)
+		}
 		err = f(buf)
 		if err != nil {
+			if hasPath(err) {
+				err = withPath(err, packedSegment(buf, pos, i, isMap, ktp, kn))
+			}
 			return err
 		}
 	}
 	return nil
 }
 
+// packedElemType get the breeze type of a packed element which type is tp. the bool elements of packed map and packed array
+// are written with their own types(TrueType or FalseType), so the type is read from the element
+func packedElemType(buf *Buffer, tp byte) (byte, error) {
+	if tp == TrueType || tp == FalseType {
+		return buf.ReadByte()
+	}
+	return tp, nil
+}
+
+// packedSegment get the path segment of a packed element which starts at pos. the key of a map element is read again for the segment
+func packedSegment(buf *Buffer, pos int, i int, isMap bool, ktp byte, kn string) string {
+	if isMap {
+		kb := &Buffer{buf: buf.buf, rpos: pos, wpos: buf.wpos, order: buf.order, temp: make([]byte, 8), context: buf.context}
+		if ktp, err := packedElemType(kb, ktp); err != nil {
+			return indexSegment(i)
+		} else if key, err := readValueByType(kb, nil, false, ktp, kn); err == nil {
+			return keySegment(key)
+		}
+	}
+	return indexSegment(i)
+}
+
 // ReadByEnum read enum with type
 func ReadByEnum(buf *Buffer, enum Enum, asAddr bool) (interface{}, error) {
 	tp, _, err := readType(buf)
@@ -335,7 +479,7 @@ func ReadByEnum(buf *Buffer, enum Enum, asAddr bool) (interface{}, error) {
 		return nil, err
 	}
 	if tp != MessageType {
-		return nil, errors.New("ReadByEnum fail, type not message, tp " + strconv.Itoa(int(tp)))
+		return nil, newTypeMismatchError(buf, enum.GetName(), TypeName(tp))
 	}
 	return enum.ReadEnum(buf, asAddr)
 }
@@ -347,19 +491,32 @@ func ReadByMessage(buf *Buffer, msg Message) (err error) {
 		return err
 	}
 	if tp != MessageType {
-		return errors.New("ReadByEnum fail, type not message, tp " + strconv.Itoa(int(tp)))
+		return newTypeMismatchError(buf, msg.GetName(), TypeName(tp))
 	}
 	return msg.ReadFrom(buf)
 }
 
+// checkMapTypes check the types of packed map with string keys, the values are read without type so the types must be same
+func checkMapTypes(buf *Buffer, vtp byte, expected string) func(ktp byte, tp byte) error {
+	return func(ktp byte, tp byte) error {
+		if ktp != StringType {
+			return newTypeMismatchError(buf, "string", TypeName(ktp))
+		}
+		if tp != vtp {
+			return newTypeMismatchError(buf, expected, TypeName(tp))
+		}
+		return nil
+	}
+}
+
 // ReadStringStringMap read map[string]string
 func ReadStringStringMap(buf *Buffer, withType bool) (m map[string]string, err error) {
-	size, err := ReadPackedSize(buf, withType)
+	size, err := ReadPackedMapSize(buf, withType)
 	if err != nil {
 		return nil, err
 	}
 	m = make(map[string]string, size)
-	err = ReadPacked(buf, size, true, func(buf *Buffer) (err error) {
+	err = readPacked(buf, size, true, checkMapTypes(buf, StringType, "string"), func(buf *Buffer) (err error) {
 		var k, v string
 		k, err = ReadStringWithoutType(buf)
 		if err != nil {
@@ -376,12 +533,12 @@ func ReadStringStringMap(buf *Buffer, withType bool) (m map[string]string, err e
 
 // ReadStringInt32Map read map[string]int32
 func ReadStringInt32Map(buf *Buffer, withType bool) (m map[string]int32, err error) {
-	size, err := ReadPackedSize(buf, withType)
+	size, err := ReadPackedMapSize(buf, withType)
 	if err != nil {
 		return nil, err
 	}
 	m = make(map[string]int32, size)
-	err = ReadPacked(buf, size, true, func(buf *Buffer) error {
+	err = readPacked(buf, size, true, checkMapTypes(buf, Int32Type, "int32"), func(buf *Buffer) error {
 		k, err := ReadStringWithoutType(buf)
 		if err != nil {
 			return err
@@ -397,12 +554,12 @@ func ReadStringInt32Map(buf *Buffer, withType bool) (m map[string]int32, err err
 
 // ReadStringInt64Map read map[string]int64
 func ReadStringInt64Map(buf *Buffer, withType bool) (m map[string]int64, err error) {
-	size, err := ReadPackedSize(buf, withType)
+	size, err := ReadPackedMapSize(buf, withType)
 	if err != nil {
 		return nil, err
 	}
 	m = make(map[string]int64, size)
-	err = ReadPacked(buf, size, true, func(buf *Buffer) error {
+	err = readPacked(buf, size, true, checkMapTypes(buf, Int64Type, "int64"), func(buf *Buffer) error {
 		k, err := ReadStringWithoutType(buf)
 		if err != nil {
 			return err
@@ -416,15 +573,29 @@ func ReadStringInt64Map(buf *Buffer, withType bool) (m map[string]int64, err err
 	return m, err
 }
 
+// readArrayElemType read the element type of packed array and check it with the expected type
+func readArrayElemType(buf *Buffer, tp byte, expected string) error {
+	etp, _, err := readType(buf)
+	if err != nil {
+		return err
+	}
+	if etp != tp {
+		return newTypeMismatchError(buf, expected, TypeName(etp))
+	}
+	return nil
+}
+
 // ReadStringArray read []string
 func ReadStringArray(buf *Buffer, withType bool) ([]string, error) {
-	size, err := ReadPackedSize(buf, withType)
+	size, err := ReadPackedArraySize(buf, withType)
 	if err != nil {
 		return nil, err
 	}
 	a := make([]string, 0, size)
 	if size > 0 {
-		readType(buf)
+		if err = readArrayElemType(buf, StringType, "string"); err != nil {
+			return nil, err
+		}
 		for i := 0; i < size; i++ {
 			s, err := ReadStringWithoutType(buf)
 			if err != nil {
@@ -438,13 +609,15 @@ func ReadStringArray(buf *Buffer, withType bool) ([]string, error) {
 
 // ReadInt32Array read []int32
 func ReadInt32Array(buf *Buffer, withType bool) ([]int32, error) {
-	size, err := ReadPackedSize(buf, withType)
+	size, err := ReadPackedArraySize(buf, withType)
 	if err != nil {
 		return nil, err
 	}
 	a := make([]int32, 0, size)
 	if size > 0 {
-		readType(buf)
+		if err = readArrayElemType(buf, Int32Type, "int32"); err != nil {
+			return nil, err
+		}
 		for i := 0; i < size; i++ {
 			i32, err := ReadInt32WithoutType(buf)
 			if err != nil {
@@ -458,13 +631,15 @@ func ReadInt32Array(buf *Buffer, withType bool) ([]int32, error) {
 
 // ReadInt64Array read []int64
 func ReadInt64Array(buf *Buffer, withType bool) ([]int64, error) {
-	size, err := ReadPackedSize(buf, withType)
+	size, err := ReadPackedArraySize(buf, withType)
 	if err != nil {
 		return nil, err
 	}
 	a := make([]int64, 0, size)
 	if size > 0 {
-		readType(buf)
+		if err = readArrayElemType(buf, Int64Type, "int64"); err != nil {
+			return nil, err
+		}
 		for i := 0; i < size; i++ {
 			i64, err := ReadInt64WithoutType(buf)
 			if err != nil {
@@ -476,18 +651,71 @@ func ReadInt64Array(buf *Buffer, withType bool) ([]int64, error) {
 	return a, nil
 }
 
+// ReadType read a breeze type from buf, it is used by the tools which walk the encoded values by themselves.
+// the schemas before a message type are read and put into the Context of buf. tp is MessageType for a message type
+// or a message type ref, and name is the message name resolved by the Context, it is empty if the ref is not found.
+func ReadType(buf *Buffer) (tp byte, name string, err error) {
+	return readType(buf)
+}
+
 func readType(buf *Buffer) (tp byte, name string, err error) {
 	tp, err = buf.ReadByte()
 	if err != nil {
 		return tp, name, err
 	}
+	for tp == SchemaType { // schema is always followed by a message type
+		schema, err := readSchema(buf)
+		if err != nil {
+			return tp, name, err
+		}
+		buf.GetContext().PutSchema(schema)
+		tp, err = buf.ReadByte()
+		if err != nil {
+			return tp, name, err
+		}
+	}
 	if tp >= MessageType { //message
 		name, err = readMessageType(buf, tp)
 		tp = MessageType
+		setReadingMessage(buf, name, nil)
 	}
 	return tp, name, err
 }
 
+// readSchema read a schema without type
+func readSchema(buf *Buffer) (*Schema, error) {
+	name, err := ReadStringWithoutType(buf)
+	if err != nil {
+		return nil, err
+	}
+	alias, err := ReadStringWithoutType(buf)
+	if err != nil {
+		return nil, err
+	}
+	size, err := ReadPackedSize(buf, false)
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{Name: name, Alias: alias}
+	for i := 0; i < size; i++ {
+		index, err := buf.ReadVarInt()
+		if err != nil {
+			return nil, err
+		}
+		field := &Field{Index: int(index)}
+		field.Name, err = ReadStringWithoutType(buf)
+		if err != nil {
+			return nil, err
+		}
+		field.Type, err = ReadStringWithoutType(buf)
+		if err != nil {
+			return nil, err
+		}
+		schema.PutFields(field)
+	}
+	return schema, nil
+}
+
 func readMessageType(buf *Buffer, tp byte) (name string, err error) {
 	if tp == MessageType {
 		name, err = ReadStringWithoutType(buf)
@@ -513,33 +741,40 @@ func ReadMessageField(buf *Buffer, readField ReadFieldsFunc) (err error) {
 		return err
 	}
 	if total > 0 {
-		pos := buf.GetRPos()
-		endPos := pos + total
+		if total > buf.Remain() {
+			return ErrNotEnough
+		}
+		name, schema := buf.readingName, buf.readingSchema
+		setReadingMessage(buf, "", nil)
+		if err = enterRead(buf); err != nil {
+			return err
+		}
+		endPos := buf.GetRPos() + total
+		wpos := buf.wpos
+		buf.wpos = endPos // fields can not be read beyond the message
+		defer func() {
+			buf.wpos = wpos
+			leaveRead(buf)
+		}()
 		var index uint64
 		for buf.GetRPos() < endPos {
 			index, err = buf.ReadVarInt()
-			if err != nil {
-				return err
+			if err == nil {
+				err = readField(buf, int(index))
 			}
-			err = readField(buf, int(index))
 			if err != nil {
+				if isNotEnough(err) {
+					return ErrWrongSize
+				}
+				if hasPath(err) {
+					err = withPath(err, fieldSegment(buf, name, schema, int(index)))
+					if buf.readDepth == 1 && name != "" { // outermost message
+						err = withPath(err, shortName(name))
+					}
+				}
 				return err
 			}
 		}
-		if buf.GetRPos() != endPos {
-			return ErrWrongSize
-		}
-	}
-	return nil
-}
-
-func checkType(buf *Buffer, expect byte) error {
-	tp, err := buf.ReadByte()
-	if err != nil {
-		return err
-	}
-	if tp != expect {
-		return errors.New("BreezeRead: wrong type expect " + strconv.Itoa(int(expect)) + ", real " + strconv.Itoa(int(tp)))
 	}
 	return nil
 }
@@ -560,102 +795,39 @@ func readValueByType(buf *Buffer, v interface{}, withType bool, t byte, msgName
 			return nil, err
 		}
 	}
-
-	// string
-	if t <= StringType {
-		var s string
-		if t == StringType {
-			s, err = ReadStringWithoutType(buf)
-		} else {
-			bytes, err := buf.Next(int(t))
-			if err != nil {
-				return nil, err
-			}
-			s = string(bytes)
-		}
-		if err != nil {
-			return nil, err
-		}
-		return adaptString(s, v)
+	if raw, ok := v.(*RawValue); ok {
+		return raw, raw.read(buf, t, msgName)
 	}
-	// int32
-	if t >= DirectInt32MinType && t <= Int32Type {
-		var i32 int32
-		if t == Int32Type {
-			i32, err = ReadInt32WithoutType(buf)
-		} else {
-			i32 = int32(t) - Int32Zero
-		}
-		if err != nil {
-			return nil, err
-		}
-		return adaptInt32(i32, v)
+	if rt, isType := v.(reflect.Type); isType {
+		return getCodec(rt).decode(buf, t, msgName)
 	}
-	//int64
-	if t >= DirectInt64MinType && t <= Int64Type {
-		var i64 int64
-		if t == Int64Type {
-			i64, err = ReadInt64WithoutType(buf)
-		} else {
-			i64 = int64(t) - Int64Zero
-		}
+	return readValueDefault(buf, v, t, msgName)
+}
+
+// readValueDefault read a value which breeze type is t from buffer, and adapt the value to v
+func readValueDefault(buf *Buffer, v interface{}, t byte, msgName string) (interface{}, error) {
+	if isScalarType(t) {
+		sv, err := readScalar(buf, t)
 		if err != nil {
 			return nil, err
 		}
-		return adaptInt64(i64, v)
+		return adaptScalar(buf, sv, v)
 	}
-
 	switch t {
 	case NullType:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Ptr {
+			rv.Elem().Set(reflect.Zero(rv.Elem().Type())) // optional value is set to nil
+		}
 		return nil, nil
 	case MessageType:
+		setReadingMessage(buf, msgName, nil)
 		return readMessage(buf, v, msgName)
 	case MapType, PackedMapType:
 		return readMap(buf, v, t == PackedMapType)
 	case ArrayType, PackedArrayType:
 		return readArray(buf, v, t == PackedArrayType)
-	case TrueType:
-		if castV, ok := v.(*bool); ok {
-			*castV = true
-		}
-		return true, nil
-	case FalseType:
-		if castV, ok := v.(*bool); ok {
-			*castV = false
-		}
-		return false, nil
-	case Float32Type:
-		f, err := ReadFloat32WithoutType(buf)
-		if err != nil {
-			return nil, err
-		}
-		return adaptFloat32(f, v)
-	case Float64Type:
-		f, err := ReadFloat64WithoutType(buf)
-		if err != nil {
-			return nil, err
-		}
-		return adaptFloat64(f, v)
-	case BytesType:
-		bytes, err := ReadBytesWithoutType(buf)
-		if err != nil {
-			return nil, err
-		}
-		if castV, ok := v.(*[]byte); ok {
-			*castV = bytes
-			return *castV, nil
-		}
-		return bytes, nil
-	case ByteType:
-		return adaptByte(buf, v)
-	case Int16Type:
-		i16, err := ReadInt16WithoutType(buf)
-		if err != nil {
-			return nil, err
-		}
-		return adaptInt16(i16, v)
 	}
-	return nil, errors.New("BreezeRead: unsupported type " + strconv.Itoa(int(t)))
+	return nil, newUnknownTypeError(buf, targetName(v), t)
 }
 
 func readMessage(buf *Buffer, v interface{}, name string) (interface{}, error) {
@@ -672,16 +844,16 @@ func readMessage(buf *Buffer, v interface{}, name string) (interface{}, error) {
 	message, ok := v.(Message)
 	if ok {
 		if message.GetName() != name && message.GetAlias() != name {
-			return nil, errors.New("BreezeRead: wrong message type. expect " + message.GetName() + ", real " + name)
+			return nil, newTypeMismatchError(buf, message.GetName(), name)
 		}
 	} else if v == nil || reflect.TypeOf(v).Kind() == reflect.Interface {
-		message = &GenericMessage{Name: name}
+		return readUnknownMessage(buf, name)
 	} else if rt, isType := v.(reflect.Type); isType {
 		if rt.Kind() == reflect.Ptr {
 			rt = rt.Elem()
 		}
 		if rt.Kind() == reflect.Interface {
-			message = &GenericMessage{Name: name}
+			return readUnknownMessage(buf, name)
 		} else {
 			newValue := reflect.New(rt).Interface()
 			if enum, ok := newValue.(Enum); ok {
@@ -689,18 +861,40 @@ func readMessage(buf *Buffer, v interface{}, name string) (interface{}, error) {
 			}
 			message, _ = newValue.(Message)
 		}
+	} else if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Struct {
+		structName, alias := getStructName(rv.Elem().Type())
+		if structName != name && alias != name {
+			return nil, newTypeMismatchError(buf, structName, name)
+		}
+		err := readStruct(buf, rv.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
 	}
 	if message != nil {
+		setReadingMessage(buf, name, message.GetSchema())
 		err := message.ReadFrom(buf)
 		if err != nil {
 			return nil, err
 		}
 		return message, nil
 	}
-	return nil, errors.New("BreezeRead: can not read breeze message to type" + reflect.TypeOf(v).String())
+	return nil, newTypeMismatchError(buf, targetName(v), name)
 }
 
 func readArray(buf *Buffer, v interface{}, isPacked bool) (interface{}, error) {
+	tp := byte(ArrayType)
+	if isPacked {
+		tp = PackedArrayType
+	}
+	rt, isType := v.(reflect.Type)
+	if v != nil && !isType { // read into a pointer
+		return readByPointer(buf, v, tp)
+	}
+	if isType && rt.Kind() != reflect.Interface {
+		return nil, newTypeMismatchError(buf, rt.String(), TypeName(tp))
+	}
 	total, err := buf.ReadVarInt()
 	if err != nil {
 		return nil, err
@@ -708,41 +902,14 @@ func readArray(buf *Buffer, v interface{}, isPacked bool) (interface{}, error) {
 	if total <= 0 {
 		return nil, nil
 	}
-	size := int(total)
-	if size > MaxElemSize {
-		return nil, errors.New("collection elem size overflow. size:" + strconv.Itoa(size))
-	}
-	var orgRv reflect.Value
-	var rv reflect.Value
-	rt, isType := v.(reflect.Type)
-	if !isType {
-		if v == nil {
-			tmp := make([]interface{}, 0, size)
-			rv = reflect.ValueOf(&tmp)
-		} else {
-			rv = reflect.ValueOf(v)
-		}
-		rt = rv.Type()
-		if rt.Kind() != reflect.Ptr {
-			return nil, errors.New("BreezeRead: can not read slice to type " + rt.String())
-		}
-		orgRv = rv
-		rv = rv.Elem()
-		rt = rv.Type()
-	}
-	if rt.Kind() != reflect.Slice && rt.Kind() != reflect.Interface {
-		return nil, errors.New("BreezeRead: can not read slice to type " + rt.String())
+	size, err := checkElemSize(buf, total)
+	if err != nil {
+		return nil, err
 	}
-	if isType {
-		if rt.Kind() == reflect.Interface {
-			rv = reflect.ValueOf(make([]interface{}, 0, size))
-			rt = rv.Type()
-		} else {
-			rv = reflect.MakeSlice(rt, 0, size)
-		}
+	if err = enterRead(buf); err != nil {
+		return nil, err
 	}
-
-	var tp byte
+	defer leaveRead(buf)
 	var name string
 	if isPacked {
 		tp, name, err = readType(buf)
@@ -750,26 +917,37 @@ func readArray(buf *Buffer, v interface{}, isPacked bool) (interface{}, error) {
 			return nil, err
 		}
 	}
-
+	a := make([]interface{}, 0, size)
 	var sv interface{}
 	for i := 0; i < size; i++ {
 		if isPacked {
-			sv, err = readValueByType(buf, rt.Elem(), false, tp, name)
+			var etp byte
+			if etp, err = packedElemType(buf, tp); err == nil {
+				sv, err = readValueByType(buf, interfaceType, false, etp, name)
+			}
 		} else {
-			sv, err = ReadValue(buf, rt.Elem())
+			sv, err = ReadValue(buf, interfaceType)
 		}
 		if err != nil {
-			return nil, err
+			return nil, withPath(err, indexSegment(i))
 		}
-		rv = reflect.Append(rv, reflect.ValueOf(sv))
+		a = append(a, sv)
 	}
-	if !isType {
-		orgRv.Elem().Set(rv)
-	}
-	return rv.Interface(), nil
+	return a, nil
 }
 
 func readMap(buf *Buffer, v interface{}, isPacked bool) (interface{}, error) {
+	tp := byte(MapType)
+	if isPacked {
+		tp = PackedMapType
+	}
+	rt, isType := v.(reflect.Type)
+	if v != nil && !isType { // read into a pointer
+		return readByPointer(buf, v, tp)
+	}
+	if isType && rt.Kind() != reflect.Interface {
+		return nil, newTypeMismatchError(buf, rt.String(), TypeName(tp))
+	}
 	total, err := buf.ReadVarInt()
 	if err != nil {
 		return nil, err
@@ -777,39 +955,14 @@ func readMap(buf *Buffer, v interface{}, isPacked bool) (interface{}, error) {
 	if total <= 0 {
 		return nil, nil
 	}
-	size := int(total)
-	if size > MaxElemSize {
-		return nil, errors.New("collection elem size overflow. size:" + strconv.Itoa(size))
-	}
-	var orgRv reflect.Value
-	var rv reflect.Value
-	rt, isType := v.(reflect.Type)
-	if !isType {
-		if v == nil {
-			tmp := make(map[interface{}]interface{}, size)
-			rv = reflect.ValueOf(&tmp)
-		} else {
-			rv = reflect.ValueOf(v)
-		}
-		rt = rv.Type()
-		if rt.Kind() != reflect.Ptr {
-			return nil, errors.New("BreezeRead: can not read map to type " + rt.String())
-		}
-		orgRv = rv
-		rv = rv.Elem()
-		rt = rv.Type()
-	}
-	if rt.Kind() != reflect.Map && rt.Kind() != reflect.Interface {
-		return nil, errors.New("BreezeRead: can not read map to type " + rt.String())
+	size, err := checkElemSize(buf, total)
+	if err != nil {
+		return nil, err
 	}
-	if isType {
-		if rt.Kind() == reflect.Interface {
-			rv = reflect.ValueOf(make(map[interface{}]interface{}, size))
-			rt = rv.Type()
-		} else {
-			rv = reflect.MakeMapWithSize(rt, size)
-		}
+	if err = enterRead(buf); err != nil {
+		return nil, err
 	}
+	defer leaveRead(buf)
 	var ktp, vtp byte
 	var kn, vn string
 	if isPacked {
@@ -822,62 +975,89 @@ func readMap(buf *Buffer, v interface{}, isPacked bool) (interface{}, error) {
 			return nil, err
 		}
 	}
-
+	m := make(map[interface{}]interface{}, size)
 	var mk, mv interface{}
 	for i := 0; i < size; i++ {
 		if isPacked {
-			mk, err = readValueByType(buf, rt.Key(), false, ktp, kn)
+			etp, err := packedElemType(buf, ktp)
+			if err == nil {
+				mk, err = readValueByType(buf, interfaceType, false, etp, kn)
+			}
 			if err != nil {
 				return nil, err
 			}
-			mv, err = readValueByType(buf, rt.Elem(), false, vtp, vn)
+			if etp, err = packedElemType(buf, vtp); err == nil {
+				mv, err = readValueByType(buf, interfaceType, false, etp, vn)
+			}
 			if err != nil {
-				return nil, err
+				return nil, withPath(err, keySegment(mk))
 			}
 		} else {
-			mk, err = ReadValue(buf, rt.Key())
+			mk, err = ReadValue(buf, interfaceType)
 			if err != nil {
 				return nil, err
 			}
-			mv, err = ReadValue(buf, rt.Elem())
+			mv, err = ReadValue(buf, interfaceType)
 			if err != nil {
-				return nil, err
+				return nil, withPath(err, keySegment(mk))
 			}
 		}
-		rv.SetMapIndex(reflect.ValueOf(mk), reflect.ValueOf(mv))
-	}
-	if !isType {
-		orgRv.Elem().Set(rv)
+		if err = checkMapKey(mk); err != nil {
+			return nil, err
+		}
+		m[mk] = mv
 	}
-	return rv.Interface(), nil
+	return m, nil
 }
 
-// ReadFloat64WithoutType read without type
-func ReadFloat64WithoutType(buf *Buffer) (float64, error) {
-	i, err := buf.ReadUint64()
+// readByPointer read a value by the codec of pointer's element type, and set the value into the pointer
+func readByPointer(buf *Buffer, v interface{}, tp byte) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("breeze: can not read value to type " + targetName(v))
+	}
+	elemType := rv.Type().Elem()
+	ret, err := getCodec(elemType).decode(buf, tp, "")
+	if err != nil || ret == nil {
+		return ret, err
+	}
+	nv, err := toValue(buf, ret, elemType)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return math.Float64frombits(i), nil
-
+	rv.Elem().Set(nv)
+	return ret, nil
 }
 
-func adaptFloat64(f float64, v interface{}) (interface{}, error) {
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// targetName get the type name of the receiver v of ReadValue
+func targetName(v interface{}) string {
 	if v == nil {
-		return f, nil
+		return "interface {}"
 	}
-	if castV, ok := v.(*float64); ok {
-		*castV = f
-		return *castV, nil
+	if rt, ok := v.(reflect.Type); ok {
+		return rt.String()
 	}
-	rt, isType := v.(reflect.Type)
-	if isType && (rt.Kind() == reflect.Float64 || rt.Kind() == reflect.Interface) {
-		return f, nil
+	return reflect.TypeOf(v).String()
+}
+
+// checkMapKey check whether a key read into interface can be used as map key
+func checkMapKey(k interface{}) error {
+	if k != nil && !reflect.TypeOf(k).Comparable() {
+		return errors.New("breeze: can not use " + reflect.TypeOf(k).String() + " as map key")
 	}
-	if !isType {
-		rt = reflect.TypeOf(v)
+	return nil
+}
+
+// ReadFloat64WithoutType read without type
+func ReadFloat64WithoutType(buf *Buffer) (float64, error) {
+	i, err := buf.ReadUint64()
+	if err != nil {
+		return 0, err
 	}
-	return nil, errors.New("BreezeRead: can not read float64 to type " + rt.String())
+	return math.Float64frombits(i), nil
+
 }
 
 // ReadFloat32WithoutType read without type
@@ -889,115 +1069,37 @@ func ReadFloat32WithoutType(buf *Buffer) (float32, error) {
 	return math.Float32frombits(i), nil
 }
 
-func adaptFloat32(f float32, v interface{}) (interface{}, error) {
-	if v == nil {
-		return f, nil
-	}
-	if castV, ok := v.(*float32); ok {
-		*castV = f
-		return *castV, nil
-	}
-	rt, isType := v.(reflect.Type)
-	if isType && (rt.Kind() == reflect.Float32 || rt.Kind() == reflect.Interface) {
-		return f, nil
-	}
-	if !isType {
-		rt = reflect.TypeOf(v)
-	}
-	return nil, errors.New("BreezeRead: can not read float32 to type " + rt.String())
-}
-
 // ReadInt64WithoutType read without type
 func ReadInt64WithoutType(buf *Buffer) (int64, error) {
 	i, err := buf.ReadZigzag64()
 	return int64(i), err
 }
 
-func adaptInt64(i int64, v interface{}) (interface{}, error) {
-	if v == nil {
-		return i, nil
-	}
-	if castV, ok := v.(*int64); ok {
-		*castV = i
-		return *castV, nil
-	}
-	rt, isType := v.(reflect.Type)
-	if isType && (rt.Kind() == reflect.Int64 || rt.Kind() == reflect.Interface) {
-		return i, nil
-	}
-	return adaptToInt(i, v)
-}
-
 // ReadInt32WithoutType read without type
 func ReadInt32WithoutType(buf *Buffer) (int32, error) {
 	i, err := buf.ReadZigzag32()
 	return int32(i), err
 }
 
-func adaptInt32(i int32, v interface{}) (interface{}, error) {
-	if v == nil {
-		return i, nil
-	}
-	if castV, ok := v.(*int); ok {
-		*castV = int(i)
-		return *castV, nil
-	}
-	if castV, ok := v.(*int32); ok {
-		*castV = i
-		return *castV, nil
-	}
-	rt, isType := v.(reflect.Type)
-	if isType {
-		if rt.Kind() == reflect.Int || rt.Kind() == reflect.Interface {
-			return int(i), nil
-		} else if rt.Kind() == reflect.Int32 {
-			return i, nil
-		}
-	}
-	return adaptToInt(int64(i), v)
-}
-
 // ReadInt16WithoutType read without type
 func ReadInt16WithoutType(buf *Buffer) (int16, error) {
 	i, err := buf.ReadUint16()
 	return int16(i), err
 }
 
-func adaptInt16(i int16, v interface{}) (interface{}, error) {
-	if v == nil {
-		return i, nil
-	}
-	if castV, ok := v.(*int16); ok {
-		*castV = i
-		return *castV, nil
-	}
-	rt, isType := v.(reflect.Type)
-	if isType && (rt.Kind() == reflect.Int16 || rt.Kind() == reflect.Interface) {
-		return i, nil
-	}
-	return adaptToInt(int64(i), v)
-}
-
 // ReadBytesWithoutType read without type
 func ReadBytesWithoutType(buf *Buffer) ([]byte, error) {
-	size, err := buf.ReadInt()
+	size, err := buf.ReadUint32()
 	if err != nil {
 		return nil, err
 	}
-	ret := make([]byte, size)
-	err = buf.ReadFull(ret)
-	return ret, err
-}
-
-func adaptByte(buf *Buffer, v interface{}) (interface{}, error) {
-	b, err := buf.ReadByte()
+	n, err := checkLength(buf, uint64(size), false)
 	if err != nil {
 		return nil, err
 	}
-	if castV, ok := v.(*byte); ok {
-		*castV = b
-	}
-	return b, nil
+	ret := make([]byte, n)
+	err = buf.ReadFull(ret)
+	return ret, err
 }
 
 // ReadStringWithoutType read without type
@@ -1006,96 +1108,9 @@ func ReadStringWithoutType(buf *Buffer) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	bytes, err := buf.Next(int(size))
+	bytes, err := readStringBytes(buf, size)
 	if err != nil {
 		return "", err
 	}
 	return string(bytes), nil
 }
-
-func adaptString(s string, v interface{}) (interface{}, error) {
-	if v == nil {
-		return s, nil
-	}
-	if castV, ok := v.(*string); ok {
-		*castV = s
-		return s, nil
-	}
-	rt, isType := v.(reflect.Type)
-	if isType && (rt.Kind() == reflect.String || rt.Kind() == reflect.Interface) {
-		return s, nil
-	}
-	//compatible with other type
-	if isType {
-		return parseStringByType(s, rt)
-	}
-	rv := reflect.ValueOf(v)
-	if rv.CanSet() && rv.Type().Kind() == reflect.Ptr {
-		tmp, err := parseStringByType(s, rv.Type().Elem())
-		if err != nil {
-			return nil, err
-		}
-		rv.Set(reflect.ValueOf(tmp))
-		return tmp, nil
-	}
-	return nil, errors.New("can not read string to type " + rv.Type().String())
-}
-
-func parseStringByType(s string, rt reflect.Type) (interface{}, error) {
-	switch rt.Kind() {
-	case reflect.Slice:
-		if rt.Elem().Kind() == reflect.Uint8 {
-			return []byte(s), nil
-		}
-	case reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32, reflect.Int, reflect.Uint, reflect.Int64, reflect.Uint64:
-		i, err := strconv.ParseInt(s, 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		return getIntByKind(i, rt.Kind())
-	case reflect.Float32:
-		return strconv.ParseFloat(s, 32)
-	case reflect.Float64:
-		return strconv.ParseFloat(s, 64)
-	}
-	return nil, errors.New("BreezeRead: can not read string to type " + rt.String())
-}
-
-func adaptToInt(i int64, v interface{}) (interface{}, error) {
-	if rt, isType := v.(reflect.Type); isType {
-		return getIntByKind(int64(i), rt.Kind())
-	}
-	rv := reflect.ValueOf(v)
-	if rv.CanSet() && rv.Type().Kind() == reflect.Ptr {
-		tmp, err := getIntByKind(int64(i), rv.Type().Elem().Kind())
-		if err != nil {
-			return nil, err
-		}
-		rv.Set(reflect.ValueOf(tmp))
-		return tmp, nil
-	}
-	return nil, errors.New("BreezeRead: can not read int to type " + rv.Type().String())
-}
-
-func getIntByKind(i int64, k reflect.Kind) (interface{}, error) {
-	switch k {
-	case reflect.Int16:
-		return int16(i), nil
-	case reflect.Uint16:
-		return uint16(i), nil
-	case reflect.Int32:
-		return int32(i), nil
-	case reflect.Uint32:
-		return uint32(i), nil
-	case reflect.Int: // for compatible with other language, int is regarded as int32 in breeze. u should use int64 if value over int32
-		return int(int32(i)), nil
-	case reflect.Uint:
-		return uint(i), nil
-	case reflect.Int64:
-		return i, nil
-	case reflect.Uint64:
-		return uint64(i), nil
-	default:
-		return nil, errors.New("BreezeRead: can not convert to type " + k.String())
-	}
-}
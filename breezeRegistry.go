@@ -0,0 +1,92 @@
+package breeze
+
+import (
+	"reflect"
+	"sync"
+)
+
+// registry of breeze messages and enums. it is used to decode messages into concrete types
+// when the receiver type is unknown, such as ReadValue(buf, nil) and the interface{} elements of maps and arrays.
+var (
+	registryLock sync.RWMutex
+	messageTypes = make(map[string]reflect.Type, DefaultSize) // message name or alias -> message type
+	enums        = make(map[string]Enum, DefaultSize)         // enum name or alias -> enum
+)
+
+// RegisterMessage register a breeze message by its name and alias. message should be a pointer such as &TestMsg{}.
+// the message registered later will replace the former one with same name.
+func RegisterMessage(message Message) {
+	if message == nil {
+		return
+	}
+	t := reflect.TypeOf(message)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, name := range []string{message.GetName(), message.GetAlias()} {
+		if name != "" {
+			messageTypes[name] = t
+		}
+	}
+}
+
+// RegisterEnum register a breeze enum by its name and alias. the enum will be read as pointer, because enum should be declared as pointer.
+func RegisterEnum(enum Enum) {
+	if enum == nil {
+		return
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, name := range []string{enum.GetName(), enum.GetAlias()} {
+		if name != "" {
+			enums[name] = enum
+		}
+	}
+}
+
+// newRegisteredMessage create a new message by registered message name, return nil if not found
+func newRegisteredMessage(name string) Message {
+	registryLock.RLock()
+	t := messageTypes[name]
+	registryLock.RUnlock()
+	if t == nil {
+		return nil
+	}
+	return reflect.New(t).Interface().(Message)
+}
+
+func getRegisteredEnum(name string) Enum {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return enums[name]
+}
+
+// getRegisteredSchema get the schema of registered message or enum
+func getRegisteredSchema(name string) *Schema {
+	if enum := getRegisteredEnum(name); enum != nil {
+		return enum.GetSchema()
+	}
+	if message := newRegisteredMessage(name); message != nil {
+		return message.GetSchema()
+	}
+	return nil
+}
+
+// readUnknownMessage read a message which receiver type is unknown. the message will be read as registered type if the name is registered, otherwise as GenericMessage
+func readUnknownMessage(buf *Buffer, name string) (interface{}, error) {
+	if enum := getRegisteredEnum(name); enum != nil {
+		return enum.ReadEnum(buf, true)
+	}
+	message := newRegisteredMessage(name)
+	if message == nil {
+		message = &GenericMessage{Name: name, schema: buf.GetContext().GetSchema(name)}
+	}
+	setReadingMessage(buf, name, message.GetSchema())
+	err := message.ReadFrom(buf)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
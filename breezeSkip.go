@@ -0,0 +1,123 @@
+package breeze
+
+// SkipValue skip a value with type in buffer without creating it, such as the unknown fields of messages.
+// the message types and schemas in the value are still put into the Context, so the later ref messages can be read.
+func SkipValue(buf *Buffer) error {
+	tp, _, err := readType(buf)
+	if err != nil {
+		return err
+	}
+	return skipValueByType(buf, tp)
+}
+
+// skipField skip a field of message, it is the ReadFieldsFunc to skip all fields
+func skipField(buf *Buffer, index int) error {
+	return SkipValue(buf)
+}
+
+// skipValueByType skip a value which breeze type is tp, the type has been read
+func skipValueByType(buf *Buffer, tp byte) error {
+	switch {
+	case tp <= DirectStringMaxType:
+		return skip(buf, uint64(tp))
+	case tp == StringType:
+		size, err := buf.ReadVarInt()
+		if err != nil {
+			return err
+		}
+		return skip(buf, size)
+	case tp >= DirectInt32MinType && tp <= DirectInt32MaxType, tp >= DirectInt64MinType && tp <= DirectInt64MaxType:
+		return nil
+	case tp == Int32Type || tp == Int64Type:
+		_, err := buf.ReadVarInt()
+		return err
+	}
+	switch tp {
+	case NullType, TrueType, FalseType:
+		return nil
+	case ByteType:
+		return skip(buf, 1)
+	case Int16Type:
+		return skip(buf, 2)
+	case Float32Type:
+		return skip(buf, 4)
+	case Float64Type:
+		return skip(buf, 8)
+	case BytesType:
+		size, err := buf.ReadUint32()
+		if err != nil {
+			return err
+		}
+		return skip(buf, uint64(size))
+	case MessageType:
+		return ReadMessageField(buf, skipField)
+	case MapType, PackedMapType, ArrayType, PackedArrayType:
+		return skipCollection(buf, tp)
+	}
+	return newUnknownTypeError(buf, "value", tp)
+}
+
+// skipCollection skip the elements of map or array
+func skipCollection(buf *Buffer, tp byte) (err error) {
+	total, err := buf.ReadVarInt()
+	if err != nil || total == 0 {
+		return err
+	}
+	if total > uint64(buf.Remain()) { // every element takes one byte at least
+		return ErrNotEnough
+	}
+	if err = enterRead(buf); err != nil {
+		return err
+	}
+	defer leaveRead(buf)
+	size := int(total)
+	isMap := tp == MapType || tp == PackedMapType
+	if tp == MapType || tp == ArrayType {
+		if isMap {
+			size *= 2
+		}
+		for i := 0; i < size; i++ {
+			if err = SkipValue(buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ktp, _, err := readType(buf) // key type of map or element type of array
+	if err != nil {
+		return err
+	}
+	vtp := ktp
+	if isMap {
+		if vtp, _, err = readType(buf); err != nil { // value type
+			return err
+		}
+	}
+	for i := 0; i < size; i++ {
+		if err = skipPackedElem(buf, ktp); err != nil {
+			return err
+		}
+		if isMap {
+			if err = skipPackedElem(buf, vtp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func skipPackedElem(buf *Buffer, tp byte) error {
+	tp, err := packedElemType(buf, tp)
+	if err != nil {
+		return err
+	}
+	return skipValueByType(buf, tp)
+}
+
+func skip(buf *Buffer, size uint64) error {
+	if size > uint64(buf.Remain()) {
+		return ErrNotEnough
+	}
+	buf.rpos += int(size)
+	return nil
+}
@@ -0,0 +1,135 @@
+package breeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkipValue(t *testing.T) {
+	values := []interface{}{nil, true, "string", string(make([]byte, 100)), byte(3), []byte("bytes"), int16(-12), int32(3), int32(1234),
+		int64(2), int64(-456789), float32(1.23), float64(-4.56), []string{"a", "b"}, []bool{true, false}, map[string]bool{"a": false},
+		map[interface{}]interface{}{"a": int32(1), int64(2): []interface{}{"x", true, nil}}, []interface{}{},
+		getTestMsg(), MyEnum(2), toStructMsg(getTestMsg()), &testOptionalMsg{}}
+	for _, v := range values {
+		buf := NewBuffer(256)
+		WriteValue(buf, v)
+		WriteValue(buf, int32(1234))
+		rbuf := CreateBuffer(buf.Bytes())
+		if err := SkipValue(rbuf); err != nil {
+			t.Fatalf("skip fail. value:%v, err:%v", v, err)
+		}
+		var i int32
+		if err := ReadInt32(rbuf, &i); err != nil || i != 1234 || rbuf.Remain() != 0 {
+			t.Errorf("wrong position after skip. value:%v, err:%v", v, err)
+		}
+	}
+
+	// message types and schemas in skipped values can be referred later
+	buf := NewBuffer(256)
+	buf.SetWriteSchema(true)
+	WriteValue(buf, []interface{}{getTestMsg()})
+	WriteValue(buf, getTestMsg())
+	rbuf := CreateBuffer(buf.Bytes())
+	if err := SkipValue(rbuf); err != nil {
+		t.Fatalf("skip fail. err:%v", err)
+	}
+	if rbuf.GetContext().GetSchema("motan.TestMsg") == nil {
+		t.Errorf("schema should be put into context")
+	}
+	v, err := ReadValue(rbuf, nil)
+	if g, ok := v.(*GenericMessage); err != nil || !ok || g.GetName() != "motan.TestMsg" || g.GetFieldByIndex(1) != int32(12) {
+		t.Errorf("wrong message after skip. err:%v, value:%v", err, v)
+	}
+
+	// unknown fields are skipped
+	g := &GenericMessage{Name: "motan.TestMsg"}
+	g.PutField(1, int32(12))
+	g.PutField(100, map[string][]int64{"a": {1, 2}})
+	g.PutField(101, getTestSubMsg())
+	g.PutField(2, "s")
+	buf = NewBuffer(256)
+	WriteValue(buf, g)
+	msg := &TestMsg{}
+	if _, err = ReadValue(CreateBuffer(buf.Bytes()), msg); err != nil || msg.MyInt != 12 || msg.MyString != "s" {
+		t.Errorf("wrong message with unknown fields. err:%v, value:%+v", err, msg)
+	}
+
+	// malformed data
+	for _, data := range [][]byte{{}, {StringType, 3, 'a'}, {Int16Type, 1}, {BytesType, 0, 0, 0, 9}, {MapType, 2, 0x01, 'a'},
+		{PackedArrayType, 200, StringType}, {0xc0}, {MessageType, 1, 'a', 0, 0, 0, 9}} {
+		if err = SkipValue(CreateBuffer(data)); err == nil {
+			t.Errorf("skip malformed data should fail. data:%x", data)
+		}
+	}
+	nested := NewBuffer(256)
+	for i := 0; i < 10; i++ {
+		nested.WriteByte(ArrayType)
+		nested.WriteVarInt(1)
+	}
+	nested.WriteByte(TrueType)
+	rbuf = CreateBuffer(nested.Bytes())
+	rbuf.SetDecodeLimits(&DecodeLimits{MaxDepth: 5})
+	if _, ok := SkipValue(rbuf).(*LimitError); !ok {
+		t.Errorf("skip should be limited by max depth")
+	}
+}
+
+func TestSkipValueAllocs(t *testing.T) {
+	buf := NewBuffer(256)
+	WriteValue(buf, map[string][]int32{"a": {1, 2, 3}, "b": {}})
+	WriteValue(buf, []interface{}{"x", 1.5, []byte("bytes"), map[int64]bool{1: true}})
+	WriteValue(buf, getTestSubMsg())
+	WriteValue(buf, getTestSubMsg()) // ref message
+	rbuf := CreateBuffer(buf.Bytes())
+	for i := 0; i < 3; i++ { // the message type name is read at the first time
+		SkipValue(rbuf)
+	}
+	pos := rbuf.GetRPos()
+	allocs := testing.AllocsPerRun(100, func() {
+		rbuf.SetRPos(0)
+		SkipValue(rbuf)
+		SkipValue(rbuf)
+		rbuf.SetRPos(pos)
+		for rbuf.Remain() > 0 {
+			if err := SkipValue(rbuf); err != nil {
+				t.Fatalf("skip fail. err:%v", err)
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("skip should not allocate. allocs:%v", allocs)
+	}
+}
+
+func TestReadPackedBool(t *testing.T) {
+	values := []interface{}{[]bool{true, false, true}, map[string]bool{"a": false, "b": true}, map[bool]int32{false: 1, true: 2}}
+	for _, v := range values {
+		buf := NewBuffer(64)
+		WriteValue(buf, v)
+		result, err := ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(v))
+		if err != nil || !reflect.DeepEqual(v, result) {
+			t.Errorf("wrong packed bool. err:%v, expect:%v, real:%v", err, v, result)
+		}
+		if _, err = ToJSON(CreateBuffer(buf.Bytes())); err != nil {
+			t.Errorf("to json fail. err:%v", err)
+		}
+	}
+	v, err := ReadValue(CreateBuffer([]byte{PackedArrayType, 2, TrueType, FalseType, TrueType}), nil)
+	if err != nil || !reflect.DeepEqual(v, []interface{}{false, true}) {
+		t.Errorf("wrong generic packed bool. err:%v, value:%v", err, v)
+	}
+}
+
+func BenchmarkSkipLargeMessage(b *testing.B) {
+	buf := NewBuffer(5000)
+	WriteValue(buf, GetBenchData(1000))
+	rBuffer := CreateBuffer(buf.Bytes())
+	if err := SkipValue(rBuffer); err != nil {
+		b.Fatalf("skip fail. err:%v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rBuffer.SetRPos(0)
+		SkipValue(rBuffer)
+	}
+}
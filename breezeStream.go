@@ -0,0 +1,128 @@
+package breeze
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// default size of stream buffer
+const (
+	DefaultStreamBufferSize = 4096
+)
+
+// Encoder writes a sequence of breeze values into an io.Writer.
+// all values written by an Encoder share the message type refs of context, so a Decoder with its own context is needed to read them.
+type Encoder struct {
+	w   io.Writer
+	buf *Buffer
+}
+
+// NewEncoder create an Encoder which writes to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, buf: NewBuffer(DefaultStreamBufferSize)}
+}
+
+// Buffer return the under Buffer of Encoder, it can be used to set the options of writing.
+func (e *Encoder) Buffer() *Buffer {
+	return e.buf
+}
+
+// Encode write a value into the stream, only the encoding bytes of one value are kept in memory.
+func (e *Encoder) Encode(v interface{}) error {
+	ctx := e.buf.GetContext()
+	count := ctx.messageTypeRefCount
+	e.buf.Reset()
+	err := WriteValue(e.buf, v)
+	if err == nil {
+		_, err = e.w.Write(e.buf.Bytes())
+	}
+	if err != nil { // the message types of this value are not sent
+		ctx.truncateMessageType(count)
+	}
+	return err
+}
+
+// Decoder reads a sequence of breeze values from an io.Reader. bytes are read from the reader on demand.
+type Decoder struct {
+	r   io.Reader
+	buf *Buffer
+	err error // error of reader
+}
+
+// NewDecoder create a Decoder which reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	buf := NewBuffer(DefaultStreamBufferSize)
+	return &Decoder{r: r, buf: buf}
+}
+
+// Buffer return the under Buffer of Decoder, it can be used to set the options of reading.
+func (d *Decoder) Buffer() *Buffer {
+	return d.buf
+}
+
+// Decode read the next value from the stream, the usage of v is same as ReadValue.
+// it returns io.EOF if there is no more value, and io.ErrUnexpectedEOF if the stream ends in the middle of a value.
+func (d *Decoder) Decode(v interface{}) (interface{}, error) {
+	ctx := d.buf.GetContext()
+	for d.buf.Remain() == 0 {
+		if err := d.fill(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		pos := d.buf.GetRPos()
+		count := ctx.messageTypeRefCount
+		d.buf.readDepth, d.buf.readAlloc = 0, 0 // decode limits are applied to each value
+		ret, err := ReadValue(d.buf, v)
+		if err == nil || !isNotEnough(err) {
+			return ret, err
+		}
+		// not enough bytes, read more and decode again
+		d.buf.SetRPos(pos)
+		ctx.truncateMessageType(count)
+		if err = d.fill(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+}
+
+// fill read more bytes from reader. the unread bytes are moved to the head of buffer before reading
+func (d *Decoder) fill() error {
+	if d.err != nil {
+		return d.err
+	}
+	b := d.buf
+	if b.rpos > 0 {
+		b.wpos = copy(b.buf, b.buf[b.rpos:b.wpos])
+		b.rpos = 0
+	}
+	// read at least as many bytes as buffered, so a value is decoded in O(log(n)) retries
+	size := b.wpos
+	if size < DefaultStreamBufferSize {
+		size = DefaultStreamBufferSize
+	}
+	if len(b.buf) < b.wpos+size {
+		b.grow(size)
+	}
+	n, err := d.r.Read(b.buf[b.wpos : b.wpos+size])
+	b.wpos += n
+	if err != nil {
+		d.err = err
+		if n > 0 {
+			return nil
+		}
+	}
+	if n == 0 && err == nil {
+		return io.ErrNoProgress
+	}
+	return err
+}
+
+func isNotEnough(err error) bool {
+	err = errors.Cause(err)
+	return err == ErrNotEnough || err == io.EOF
+}
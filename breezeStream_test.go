@@ -0,0 +1,61 @@
+package breeze
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	var w bytes.Buffer
+	encoder := NewEncoder(&w)
+	values := []interface{}{"string", int64(123), getTestMsg(), getTestMsg(), map[string]int32{"a": 1}, getTestSubMsg()}
+	var sizes []int
+	for _, v := range values {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatalf("encode fail. v:%v, err:%v", v, err)
+		}
+		sizes = append(sizes, encoder.Buffer().Len())
+	}
+	// the message types of second TestMsg are written as refs
+	if sizes[3] >= sizes[2] {
+		t.Errorf("message type ref not shared. first size:%d, second size:%d", sizes[2], sizes[3])
+	}
+	data := w.Bytes()
+
+	// read one byte each time
+	decoder := NewDecoder(iotest.OneByteReader(bytes.NewReader(data)))
+	for i, v := range values {
+		var result interface{}
+		var err error
+		switch v.(type) {
+		case *TestMsg:
+			result, err = decoder.Decode(&TestMsg{})
+		case *TestSubMsg:
+			result, err = decoder.Decode(&TestSubMsg{})
+		default:
+			result, err = decoder.Decode(reflect.TypeOf(v))
+		}
+		if err != nil {
+			t.Fatalf("decode fail. index:%d, err:%v", i, err)
+		}
+		if !reflect.DeepEqual(v, result) {
+			t.Errorf("decode value not correct. index:%d, expect:%v, real:%v", i, v, result)
+		}
+	}
+	if _, err := decoder.Decode(nil); err != io.EOF {
+		t.Errorf("should be io.EOF at the end of stream. err:%v", err)
+	}
+
+	// stream ends in the middle of a value
+	decoder = NewDecoder(bytes.NewReader(data[:len(data)-3]))
+	var err error
+	for err == nil {
+		_, err = decoder.Decode(nil)
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("should be io.ErrUnexpectedEOF. err:%v", err)
+	}
+}
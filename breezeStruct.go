@@ -0,0 +1,216 @@
+package breeze
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Plain go structs can be written and read as breeze message by WriteValue and ReadValue.
+The breeze field of a struct field is described by struct tag `breeze`, for example:
+
+	type User struct {
+		_     struct{}          `breeze:"name=motan.User,alias=User"`
+		ID    int64             `breeze:"1"`
+		Name  string            `breeze:"2,name=userName,omitempty"`
+		Attrs map[string]string `breeze:"3,omitempty"`
+		Temp  string            `breeze:"-"`
+	}
+
+the first part of the tag is the field index, option `name` is the field name in schema (default is the go field name with lower first letter),
+option `omitempty` means the field will not be written if it has a default value.
+the tag of blank field `_` sets the message name and alias, the default message name is the go type name, such as `breeze.User`.
+fields without tag and unexported fields are ignored.
+unsigned integers are written as wider signed integers: uint16 as int32, uint32, uint and uint64 as int64,
+writing uint or uint64 over math.MaxInt64 returns an OverflowError.
+pointer fields such as *int32 are optional fields, nil is not written and the default value is written if the pointer is not nil,
+so the receiver can tell the default value from not set.
+the codecs of a struct type are built by reflection once, but reading and writing by reflection are still slower than
+the generated message. compare BenchmarkWriteLargeStruct and BenchmarkReadLargeStruct with BenchmarkWriteLargeMessage and
+BenchmarkReadLargeMessage, and use breeze-gen for performance-sensitive code.
+*/
+
+const tagName = "breeze"
+
+type structField struct {
+	index     int // breeze field index
+	name      string
+	fieldNum  int // go field number in struct
+	num       int // position in structInfo.fields
+	omitEmpty bool
+}
+
+type structInfo struct {
+	name        string
+	alias       string
+	fields      []*structField // ordered by breeze field index
+	indexFields map[int]*structField
+	schema      *Schema
+}
+
+var structInfos sync.Map // map[reflect.Type]*structInfo
+
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if info, ok := structInfos.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+	info, err := parseStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := structInfos.LoadOrStore(t, info)
+	return actual.(*structInfo), nil
+}
+
+func parseStructInfo(t reflect.Type) (*structInfo, error) {
+	info := &structInfo{indexFields: make(map[int]*structField, t.NumField())}
+	info.name, info.alias = getStructName(t)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(tagName)
+		if !ok || tag == "-" || sf.Name == "_" || sf.PkgPath != "" { // ignore unexported field
+			continue
+		}
+		index, options := parseTag(tag)
+		idx, err := strconv.Atoi(index)
+		if err != nil || idx < 0 {
+			return nil, errors.New("breeze: wrong field index in tag of " + t.String() + "." + sf.Name + ", tag:" + tag)
+		}
+		if _, ok := info.indexFields[idx]; ok {
+			return nil, errors.New("breeze: duplicate field index " + index + " in " + t.String())
+		}
+		field := &structField{index: idx, name: options["name"], fieldNum: sf.Index[0]}
+		if field.name == "" {
+			field.name = lowerFirst(sf.Name)
+		}
+		_, field.omitEmpty = options["omitempty"]
+		info.fields = append(info.fields, field)
+		info.indexFields[idx] = field
+	}
+	sort.Slice(info.fields, func(i, j int) bool { return info.fields[i].index < info.fields[j].index })
+	info.schema = &Schema{Name: info.name, Alias: info.alias}
+	for i, f := range info.fields {
+		f.num = i
+		info.schema.PutFields(&Field{Index: f.index, Name: f.name, Type: getTypeName(t.Field(f.fieldNum).Type)})
+	}
+	return info, nil
+}
+
+// getStructName get message name and alias from the tag of blank field `_`
+func getStructName(t reflect.Type) (name string, alias string) {
+	name = t.String()
+	if sf, ok := t.FieldByName("_"); ok {
+		_, options := parseTag(sf.Tag.Get(tagName))
+		if options["name"] != "" {
+			name = options["name"]
+		}
+		alias = options["alias"]
+	}
+	return name, alias
+}
+
+// parseTag split a tag like `3,name=myMap,omitempty` into index and options
+func parseTag(tag string) (index string, options map[string]string) {
+	options = make(map[string]string, 4)
+	for i, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			options[kv[0]] = kv[1]
+		} else if i == 0 {
+			index = part
+		} else {
+			options[part] = ""
+		}
+	}
+	return index, options
+}
+
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+// getTypeName get the breeze schema type name of a go type, such as `map<string, TestSubMsg>`
+func getTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		if t.Implements(messageType) {
+			return shortName(reflect.New(t.Elem()).Interface().(Message).GetName())
+		}
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Uint8:
+		return "byte"
+	case reflect.Int16:
+		return "int16"
+	case reflect.Int, reflect.Int32, reflect.Uint16:
+		return "int32"
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "int64"
+	case reflect.Float32:
+		return "float32"
+	case reflect.Float64:
+		return "float64"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "array<" + getTypeName(t.Elem()) + ">"
+	case reflect.Map:
+		return "map<" + getTypeName(t.Key()) + ", " + getTypeName(t.Elem()) + ">"
+	case reflect.Struct:
+		name, _ := getStructName(t)
+		return shortName(name)
+	}
+	if t.Kind() != reflect.Interface && t.Implements(messageType) {
+		return shortName(reflect.Zero(t).Interface().(Message).GetName())
+	}
+	return t.String()
+}
+
+// shortName remove the package of a message name
+func shortName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// readStruct read the message fields into struct rv by the codec of struct type, the message type has been read
+func readStruct(buf *Buffer, rv reflect.Value) error {
+	return getCodec(rv.Type()).read(buf, rv)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package breeze
+
+import (
+	"sort"
+)
+
+/*
+UnknownFields keeps the fields which are unknown by a message, so the fields added by newer producers are not lost
+in a read-modify-write cycle. the generated messages embed it, read the unknown fields by ReadUnknownField in ReadFrom
+and write them back by WriteUnknownFields in WriteTo, for example:
+
+	type TestSubMsg struct {
+		MyString string
+		breeze.UnknownFields
+	}
+
+	func (t *TestSubMsg) ReadFrom(buf *breeze.Buffer) error {
+		return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+			switch index {
+			case 1:
+				err = breeze.ReadString(buf, &t.MyString)
+			default: //keep unknown field
+				err = t.ReadUnknownField(buf, index)
+			}
+			return err
+		})
+	}
+
+the unknown fields are kept as RawValue in ascending index order, and written after the known fields.
+*/
+type UnknownFields struct {
+	unknownFields []unknownField
+}
+
+type unknownField struct {
+	index int
+	value *RawValue
+}
+
+// ReadUnknownField read the value of an unknown field, the value of same index read before is replaced
+func (u *UnknownFields) ReadUnknownField(buf *Buffer, index int) error {
+	raw, err := ReadRawValue(buf)
+	if err != nil {
+		return err
+	}
+	i := u.search(index)
+	if i < len(u.unknownFields) && u.unknownFields[i].index == index {
+		u.unknownFields[i].value = raw
+		return nil
+	}
+	u.unknownFields = append(u.unknownFields, unknownField{})
+	copy(u.unknownFields[i+1:], u.unknownFields[i:])
+	u.unknownFields[i] = unknownField{index: index, value: raw}
+	return nil
+}
+
+// WriteUnknownFields write all unknown fields with index. it should be called in WriteFieldsFunc,
+// the error is kept in buffer and returned by the enclosing message
+func (u *UnknownFields) WriteUnknownFields(buf *Buffer) {
+	for _, f := range u.unknownFields {
+		buf.WriteVarInt(uint64(f.index))
+		if err := f.value.writeTo(buf); err != nil {
+			setWriteErr(buf, err)
+			return
+		}
+	}
+}
+
+// UnknownFieldIndexes get the indexes of unknown fields in ascending order
+func (u *UnknownFields) UnknownFieldIndexes() []int {
+	indexes := make([]int, 0, len(u.unknownFields))
+	for _, f := range u.unknownFields {
+		indexes = append(indexes, f.index)
+	}
+	return indexes
+}
+
+// GetUnknownField get the value of an unknown field by index, nil is returned if the field is not found
+func (u *UnknownFields) GetUnknownField(index int) *RawValue {
+	i := u.search(index)
+	if i < len(u.unknownFields) && u.unknownFields[i].index == index {
+		return u.unknownFields[i].value
+	}
+	return nil
+}
+
+// ClearUnknownFields remove all unknown fields
+func (u *UnknownFields) ClearUnknownFields() {
+	u.unknownFields = nil
+}
+
+func (u *UnknownFields) search(index int) int {
+	return sort.Search(len(u.unknownFields), func(i int) bool { return u.unknownFields[i].index >= index })
+}
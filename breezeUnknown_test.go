@@ -0,0 +1,78 @@
+package breeze
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	nested := &GenericMessage{Name: "motan.Nested"}
+	nested.PutField(1, "nested")
+	msg := &GenericMessage{Name: "motan.TestSubMsg"}
+	msg.PutField(1, "known")
+	msg.PutField(102, nested)
+	msg.PutField(100, int32(7))
+	msg.PutField(101, map[string]int32{"a": 1})
+	msg.PutField(103, nested) // ref message in unknown field
+	buf := NewBuffer(256)
+	WriteValue(buf, &GenericMessage{Name: "motan.Other"}) // the message type refs in source and target are different
+	WriteValue(buf, msg)
+
+	sub := &TestSubMsg{}
+	rbuf := CreateBuffer(buf.Bytes())
+	if _, err := ReadValue(rbuf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadValue(rbuf, sub); err != nil {
+		t.Fatal(err)
+	}
+	if sub.MyString != "known" {
+		t.Errorf("wrong known field: %s", sub.MyString)
+	}
+	if indexes := sub.UnknownFieldIndexes(); !reflect.DeepEqual(indexes, []int{100, 101, 102, 103}) {
+		t.Errorf("wrong unknown field indexes: %v", indexes)
+	}
+	if v, err := sub.GetUnknownField(100).Decode(nil); err != nil || v != int32(7) {
+		t.Errorf("wrong unknown field value. err:%v, value:%v", err, v)
+	}
+	if sub.GetUnknownField(2) != nil {
+		t.Errorf("known field should not be unknown field")
+	}
+
+	// unknown fields are written back after the known fields
+	out := NewBuffer(256)
+	WriteValue(out, sub)
+	v, err := ReadValue(CreateBuffer(out.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := v.(*GenericMessage)
+	if result.GetFieldByIndex(1) != "known" || result.GetFieldByIndex(100) != int32(7) {
+		t.Errorf("wrong fields: %v", result.fields)
+	}
+	if m, ok := result.GetFieldByIndex(101).(map[interface{}]interface{}); !ok || m["a"] != 1 {
+		t.Errorf("wrong map field: %#v", result.GetFieldByIndex(101))
+	}
+	for _, index := range []int{102, 103} {
+		if m, ok := result.GetFieldByIndex(index).(*GenericMessage); !ok || m.Name != "motan.Nested" || m.GetFieldByIndex(1) != "nested" {
+			t.Errorf("wrong message field %d: %v", index, result.GetFieldByIndex(index))
+		}
+	}
+
+	// read again and clear
+	again := &TestSubMsg{}
+	if _, err := ReadValue(CreateBuffer(out.Bytes()), again); err != nil || !reflect.DeepEqual(again.UnknownFieldIndexes(), sub.UnknownFieldIndexes()) {
+		t.Errorf("wrong unknown fields after write back. err:%v, indexes:%v", err, again.UnknownFieldIndexes())
+	}
+	again.ClearUnknownFields()
+	out = NewBuffer(256)
+	WriteValue(out, again)
+	if v, err = ReadValue(CreateBuffer(out.Bytes()), nil); err != nil || v.(*GenericMessage).GetFieldByIndex(100) != nil {
+		t.Errorf("unknown fields should be cleared. err:%v, value:%v", err, v)
+	}
+
+	// the unknown fields of last read are not kept when the message is reused
+	if _, err := ReadValue(CreateBuffer(out.Bytes()), sub); err != nil || len(sub.UnknownFieldIndexes()) != 0 {
+		t.Errorf("unknown fields of last read should be cleared. err:%v, indexes:%v", err, sub.UnknownFieldIndexes())
+	}
+}
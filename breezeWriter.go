@@ -107,7 +107,11 @@ func WritePackedMap(buf *Buffer, withType bool, size int, f WriteElemFunc) {
 		WritePackedMapType(buf)
 	}
 	buf.WriteVarInt(uint64(size))
-	f(buf)
+	// the error of entering is kept in buffer and returned by the enclosing message
+	if enterWrite(buf, 0, nil, 0) == nil {
+		defer leaveWrite(buf) // f may panic by the errors of nested fields
+		f(buf)
+	}
 }
 
 // WritePackedArray write packed array by WriteElemFunc
@@ -116,13 +120,23 @@ func WritePackedArray(buf *Buffer, withType bool, size int, f WriteElemFunc) {
 		WritePackedArrayType(buf)
 	}
 	buf.WriteVarInt(uint64(size))
-	f(buf)
+	if enterWrite(buf, 0, nil, 0) == nil {
+		defer leaveWrite(buf)
+		f(buf)
+	}
 }
 
 // WriteStringStringMapEntries write map[string]string directly
 func WriteStringStringMapEntries(buf *Buffer, m map[string]string) {
 	WriteStringType(buf)
 	WriteStringType(buf)
+	if buf.canonical {
+		for _, k := range sortedMapKeys(reflect.ValueOf(m)) {
+			WriteString(buf, k.String(), false)
+			WriteString(buf, m[k.String()], false)
+		}
+		return
+	}
 	for k, v := range m {
 		WriteString(buf, k, false)
 		WriteString(buf, v, false)
@@ -133,6 +147,13 @@ func WriteStringStringMapEntries(buf *Buffer, m map[string]string) {
 func WriteStringInt32MapEntries(buf *Buffer, m map[string]int32) {
 	WriteStringType(buf)
 	WriteInt32Type(buf)
+	if buf.canonical {
+		for _, k := range sortedMapKeys(reflect.ValueOf(m)) {
+			WriteString(buf, k.String(), false)
+			WriteInt32(buf, m[k.String()], false)
+		}
+		return
+	}
 	for k, v := range m {
 		WriteString(buf, k, false)
 		WriteInt32(buf, v, false)
@@ -143,6 +164,13 @@ func WriteStringInt32MapEntries(buf *Buffer, m map[string]int32) {
 func WriteStringInt64MapEntries(buf *Buffer, m map[string]int64) {
 	WriteStringType(buf)
 	WriteInt64Type(buf)
+	if buf.canonical {
+		for _, k := range sortedMapKeys(reflect.ValueOf(m)) {
+			WriteString(buf, k.String(), false)
+			WriteInt64(buf, m[k.String()], false)
+		}
+		return
+	}
 	for k, v := range m {
 		WriteString(buf, k, false)
 		WriteInt64(buf, v, false)
@@ -177,8 +205,10 @@ func WriteInt64ArrayElems(buf *Buffer, a []int64) {
 func WriteMessageWithoutType(buf *Buffer, fieldsFunc WriteFieldsFunc) (err error) {
 	defer func() {
 		if inner := recover(); inner != nil {
-			err = inner.(error)
+			setWriteErr(buf, inner.(error))
 		}
+		// the errors of nested messages may be ignored by generated codes, so they are kept in buffer
+		err = takeWriteErr(buf)
 	}()
 	pos := skipLength(buf)
 	fieldsFunc(buf)
@@ -245,8 +275,24 @@ func WritePackedArrayType(buf *Buffer) {
 
 // WriteMessageType write message type. it can be a ref index or message with name
 func WriteMessageType(buf *Buffer, name string) {
+	writeMessageType(buf, name, nil)
+}
+
+// writeMessageType write message type, and write the schema before the message type at the first time if the buffer enables schema writing
+func writeMessageType(buf *Buffer, name string, schema *Schema) {
 	index := buf.GetContext().getMessageTypeIndex(name)
 	if index < 0 { // first write
+		if buf.writeSchema {
+			if schema == nil {
+				schema = buf.GetContext().GetSchema(name)
+			}
+			if schema == nil {
+				schema = getRegisteredSchema(name)
+			}
+			if schema != nil {
+				WriteSchema(buf, schema)
+			}
+		}
 		buf.WriteByte(MessageType)
 		WriteString(buf, name, false)
 		buf.GetContext().putMessageType(name)
@@ -260,6 +306,20 @@ func WriteMessageType(buf *Buffer, name string) {
 	}
 }
 
+// WriteSchema write a breeze schema into the buffer. the schema is written before the message type which it describes.
+func WriteSchema(buf *Buffer, schema *Schema) {
+	buf.WriteByte(SchemaType)
+	WriteString(buf, schema.Name, false)
+	WriteString(buf, schema.Alias, false)
+	fields := schema.GetFields()
+	buf.WriteVarInt(uint64(len(fields)))
+	for _, f := range fields {
+		buf.WriteVarInt(uint64(f.Index))
+		WriteString(buf, f.Name, false)
+		WriteString(buf, f.Type, false)
+	}
+}
+
 //========== write message field by type. it will not write if the value is default =====================
 
 // WriteBoolField write field with index
@@ -332,6 +392,72 @@ func WriteFloat64Field(buf *Buffer, index int, f float64) {
 	}
 }
 
+//========== write optional message field by type. it will not write if the value is nil, and will write the default value =====================
+
+// WriteOptionalBoolField write field with index if b is not nil
+func WriteOptionalBoolField(buf *Buffer, index int, b *bool) {
+	if b != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteBool(buf, *b, true)
+	}
+}
+
+// WriteOptionalStringField write field with index if s is not nil
+func WriteOptionalStringField(buf *Buffer, index int, s *string) {
+	if s != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteString(buf, *s, true)
+	}
+}
+
+// WriteOptionalByteField write field with index if b is not nil
+func WriteOptionalByteField(buf *Buffer, index int, b *byte) {
+	if b != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteByte(buf, *b, true)
+	}
+}
+
+// WriteOptionalInt16Field write field with index if i is not nil
+func WriteOptionalInt16Field(buf *Buffer, index int, i *int16) {
+	if i != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteInt16(buf, *i, true)
+	}
+}
+
+// WriteOptionalInt32Field write field with index if i is not nil
+func WriteOptionalInt32Field(buf *Buffer, index int, i *int32) {
+	if i != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteInt32(buf, *i, true)
+	}
+}
+
+// WriteOptionalInt64Field write field with index if i is not nil
+func WriteOptionalInt64Field(buf *Buffer, index int, i *int64) {
+	if i != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteInt64(buf, *i, true)
+	}
+}
+
+// WriteOptionalFloat32Field write field with index if f is not nil
+func WriteOptionalFloat32Field(buf *Buffer, index int, f *float32) {
+	if f != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteFloat32(buf, *f, true)
+	}
+}
+
+// WriteOptionalFloat64Field write field with index if f is not nil
+func WriteOptionalFloat64Field(buf *Buffer, index int, f *float64) {
+	if f != nil {
+		buf.WriteVarInt(uint64(index))
+		WriteFloat64(buf, *f, true)
+	}
+}
+
 // WriteMapField write field with index
 func WriteMapField(buf *Buffer, index int, size int, f WriteElemFunc) {
 	buf.WriteVarInt(uint64(index))
@@ -344,11 +470,12 @@ func WriteArrayField(buf *Buffer, index int, size int, f WriteElemFunc) {
 	WritePackedArray(buf, true, size, f)
 }
 
-// WriteMessageField write field with index
+// WriteMessageField write field with index. the error is kept in buffer and returned by the enclosing message
 func WriteMessageField(buf *Buffer, index int, m Message) {
 	buf.WriteVarInt(uint64(index))
-	WriteMessageType(buf, m.GetName())
-	m.WriteTo(buf)
+	if err := writeMessage(buf, m, true); err != nil {
+		setWriteErr(buf, err)
+	}
 }
 
 // WriteField write an any type field into buffer.
@@ -383,177 +510,29 @@ func WriteValue(buf *Buffer, v interface{}) error {
 }
 
 func writeReflectValue(buf *Buffer, rv reflect.Value, withType bool) error {
-	k := rv.Kind()
-	if k == reflect.Ptr {
-		if rv.CanInterface() { //message
-			realV := rv.Interface()
-			if msg, ok := realV.(Message); ok {
-				return writeMessage(buf, msg, withType)
-			}
-		}
-		//TODO extension for custom process
-		rv = rv.Elem()
-		k = rv.Kind()
-	}
-	if k == reflect.Interface {
-		rv = reflect.ValueOf(rv.Interface())
-		k = rv.Kind()
-	}
-	switch k {
-	case reflect.String:
-		WriteString(buf, rv.String(), withType)
-	case reflect.Bool:
-		WriteBool(buf, rv.Bool(), withType)
-	case reflect.Int, reflect.Int32:
-		WriteInt32(buf, int32(rv.Int()), withType)
-	case reflect.Int64:
-		WriteInt64(buf, rv.Int(), withType)
-	case reflect.Map:
-		return writeMap(buf, rv, withType)
-	case reflect.Slice:
-		t := rv.Type().Elem().Kind()
-		if t == reflect.Uint8 {
-			WriteBytes(buf, rv.Bytes(), withType)
-		} else {
-			return writeArray(buf, rv, withType)
-		}
-	case reflect.Uint, reflect.Uint32:
-		WriteInt32(buf, int32(rv.Uint()), withType)
-	case reflect.Uint64:
-		WriteInt64(buf, int64(rv.Uint()), withType)
-	case reflect.Uint8:
-		WriteByte(buf, byte(rv.Uint()), withType)
-	case reflect.Int16:
-		WriteInt16(buf, int16(rv.Int()), withType)
-	case reflect.Uint16:
-		WriteInt16(buf, int16(rv.Uint()), withType)
-	case reflect.Float32:
-		WriteFloat32(buf, float32(rv.Float()), withType)
-	case reflect.Float64:
-		WriteFloat64(buf, rv.Float(), withType)
-	default:
-		return errors.New("breeze: unsupported type " + k.String())
+	if !rv.IsValid() {
+		return errors.New("breeze: unsupported type invalid")
 	}
-	return nil
+	return getCodec(rv.Type()).encode(buf, rv, withType)
 }
 
-func writeType(buf *Buffer, rv reflect.Value) {
-	k := rv.Kind()
-	if k == reflect.Ptr {
-		if rv.CanInterface() { //message
-			realV := rv.Interface()
-			if msg, ok := realV.(Message); ok {
-				WriteMessageType(buf, msg.GetName())
-				return
-			}
-		}
-		rv = rv.Elem()
-		k = rv.Kind()
+func writeMessage(buf *Buffer, message Message, withType bool) (err error) {
+	var ptr uintptr
+	var typ reflect.Type
+	if rv := reflect.ValueOf(message); rv.Kind() == reflect.Ptr {
+		ptr, typ = rv.Pointer(), rv.Type()
 	}
-	switch k {
-	case reflect.String:
-		WriteStringType(buf)
-	case reflect.Bool:
-		WriteBoolType(buf)
-	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
-		WriteInt32Type(buf)
-	case reflect.Int64, reflect.Uint64:
-		WriteInt64Type(buf)
-	case reflect.Map:
-		if canPackMap(rv.Type()) {
-			WritePackedMapType(buf)
-		} else {
-			buf.WriteByte(MapType)
-		}
-	case reflect.Slice:
-		tp := rv.Type()
-		if tp.Elem().Kind() == reflect.Uint8 {
-			WriteBytesType(buf)
-		} else {
-			if canPackArray(rv.Type()) {
-				WritePackedArrayType(buf)
-			} else {
-				buf.WriteByte(ArrayType)
-			}
-		}
-	case reflect.Uint8:
-		WriteByteType(buf)
-	case reflect.Int16, reflect.Uint16:
-		WriteInt16Type(buf)
-	case reflect.Float32:
-		WriteFloat32Type(buf)
-	case reflect.Float64:
-		WriteFloat64Type(buf)
-	default:
-		panic(errors.New("breeze: unsupported type " + k.String()))
+	if err = enterWrite(buf, ptr, typ, 0); err != nil {
+		return err
 	}
-}
-
-func writeArray(buf *Buffer, v reflect.Value, withType bool) (err error) {
-	if canPackArray(v.Type()) {
-		defer func() {
-			if inner := recover(); inner != nil {
-				err = inner.(error)
-			}
-		}()
-		WritePackedArray(buf, withType, v.Len(), func(buf *Buffer) {
-			for i := 0; i < v.Len(); i++ {
-				elem := v.Index(i)
-				if i == 0 {
-					writeType(buf, elem)
-				}
-				err = writeReflectValue(buf, elem, false)
-				if err != nil {
-					panic(err)
-				}
-			}
-		})
-	} else {
-		if withType {
-			buf.WriteByte(ArrayType)
-		}
-		buf.WriteVarInt(uint64(v.Len()))
-		for i := 0; i < v.Len(); i++ {
-			err = writeReflectValue(buf, v.Index(i), true)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return err
-}
-
-func writeMap(buf *Buffer, v reflect.Value, withType bool) (err error) {
-	if canPackMap(v.Type()) {
-		defer func() {
-			if inner := recover(); inner != nil {
-				err = inner.(error)
-			}
-		}()
-		WritePackedMap(buf, withType, v.Len(), func(buf *Buffer) {
-			rangePackedMap(buf, v)
-		})
-	} else {
-		if withType {
-			buf.WriteByte(MapType)
+	// leave in defer, the WriteTo of custom message may panic
+	defer func() {
+		if lerr := leaveWrite(buf); err == nil {
+			err = lerr
 		}
-		buf.WriteVarInt(uint64(v.Len()))
-		err = rangeMap(buf, v)
-	}
-	return err
-}
-
-func canPackArray(t reflect.Type) bool {
-	return t.Elem().Kind() != reflect.Interface
-}
-
-func canPackMap(t reflect.Type) bool {
-	return (t.Key().Kind() != reflect.Interface) && (t.Elem().Kind() != reflect.Interface)
-}
-
-func writeMessage(buf *Buffer, message Message, withType bool) error {
+	}()
 	if withType {
-		WriteMessageType(buf, message.GetName())
+		writeMessageType(buf, message.GetName(), message.GetSchema())
 	}
 	return message.WriteTo(buf)
 }
@@ -572,3 +551,59 @@ func writeLength(buf *Buffer, keepPos int) {
 	buf.WriteUint32(uint32(curPos - keepPos - 4))
 	buf.SetWPos(curPos)
 }
+
+// writeRef is a message, map or array being written. the type distinguishes a struct from its first field which has the same
+// address, and the size distinguishes the slices with same data pointer
+type writeRef struct {
+	ptr  uintptr
+	typ  reflect.Type
+	size int
+}
+
+// enterWrite check the write depth and circular reference before writing a nested value.
+// ptr is the pointer of the value which type is typ, 0 means the value can not be referenced circularly.
+// leaveWrite must be called after the value is written if no error returned.
+func enterWrite(buf *Buffer, ptr uintptr, typ reflect.Type, size int) error {
+	if buf.writeErr == nil {
+		maxDepth := buf.maxWriteDepth
+		if maxDepth <= 0 {
+			maxDepth = MaxWriteDepth
+		}
+		if len(buf.writeRefs) >= maxDepth {
+			buf.writeErr = ErrMaxDepth
+		} else if ptr != 0 {
+			ref := writeRef{ptr: ptr, typ: typ, size: size}
+			for _, r := range buf.writeRefs {
+				if r == ref {
+					buf.writeErr = ErrCircularReference
+					break
+				}
+			}
+		}
+	}
+	if buf.writeErr != nil {
+		return takeWriteErr(buf)
+	}
+	buf.writeRefs = append(buf.writeRefs, writeRef{ptr: ptr, typ: typ, size: size})
+	return nil
+}
+
+func leaveWrite(buf *Buffer) error {
+	buf.writeRefs = buf.writeRefs[:len(buf.writeRefs)-1]
+	return takeWriteErr(buf)
+}
+
+func setWriteErr(buf *Buffer, err error) {
+	if buf.writeErr == nil {
+		buf.writeErr = err
+	}
+}
+
+// takeWriteErr get the kept write error. the error is cleared when the outermost value is written
+func takeWriteErr(buf *Buffer) error {
+	err := buf.writeErr
+	if len(buf.writeRefs) == 0 {
+		buf.writeErr = nil
+	}
+	return err
+}
@@ -1,10 +1,14 @@
 package breeze
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -566,6 +570,799 @@ func TestWriteValueMessage(t *testing.T) {
 	}
 }
 
+type testStructSubMsg struct {
+	_         struct{}          `breeze:"name=motan.TestSubMsg"`
+	MyString  string            `breeze:"1,omitempty"`
+	MyInt     int32             `breeze:"2,omitempty"`
+	MyInt64   int64             `breeze:"3,omitempty"`
+	MyFloat32 float32           `breeze:"4,omitempty"`
+	MyFloat64 float64           `breeze:"5,omitempty"`
+	MyByte    byte              `breeze:"6"`
+	MyBytes   []byte            `breeze:"7,omitempty"`
+	MyMap1    map[string][]byte `breeze:"8,omitempty"`
+	MyMap2    map[int32][]int32 `breeze:"9,omitempty"`
+	MyArray   []int32           `breeze:"10,omitempty"`
+	MyBool    bool              `breeze:"11,omitempty"`
+	Ignored   string
+	Skipped   string `breeze:"-"`
+}
+
+type testStructMsg struct {
+	_         struct{}                     `breeze:"name=motan.TestMsg"`
+	MyInt     int32                        `breeze:"1,omitempty"`
+	MyString  string                       `breeze:"2,omitempty"`
+	MyMap     map[string]*testStructSubMsg `breeze:"3,omitempty"`
+	MyArray   []*testStructSubMsg          `breeze:"4,omitempty"`
+	SubMsg    *testStructSubMsg            `breeze:"5"`
+	MyEnum    *MyEnum                      `breeze:"6"`
+	EnumArray []*MyEnum                    `breeze:"7,omitempty"`
+}
+
+func toStructSubMsg(m *TestSubMsg) *testStructSubMsg {
+	return &testStructSubMsg{MyString: m.MyString, MyInt: m.MyInt, MyInt64: m.MyInt64, MyFloat32: m.MyFloat32, MyFloat64: m.MyFloat64,
+		MyByte: m.MyByte, MyBytes: m.MyBytes, MyMap1: m.MyMap1, MyMap2: m.MyMap2, MyArray: m.MyArray, MyBool: m.MyBool}
+}
+
+func toStructMsg(m *TestMsg) *testStructMsg {
+	sm := &testStructMsg{MyInt: m.MyInt, MyString: m.MyString, MyEnum: m.MyEnum, EnumArray: m.EnumArray}
+	if m.MyMap != nil {
+		sm.MyMap = make(map[string]*testStructSubMsg, len(m.MyMap))
+		for k, v := range m.MyMap {
+			sm.MyMap[k] = toStructSubMsg(v)
+		}
+	}
+	for _, v := range m.MyArray {
+		sm.MyArray = append(sm.MyArray, toStructSubMsg(v))
+	}
+	if m.SubMsg != nil {
+		sm.SubMsg = toStructSubMsg(m.SubMsg)
+	}
+	return sm
+}
+
+// getSingleEntryTestMsg returns a TestMsg whose maps have only one entry, so the encoded bytes are stable
+func getSingleEntryTestMsg() *TestMsg {
+	msg := getTestMsg()
+	sub := msg.MyMap["m1"]
+	sub.MyMap1 = map[string][]byte{"jdie": []byte("ierjkkkd")}
+	sub.MyMap2 = map[int32][]int32{3: {34, 45, 657}}
+	msg.SubMsg = getTestSubMsgByInt(-7)
+	msg.SubMsg.MyMap1 = nil
+	msg.SubMsg.MyMap2 = nil
+	return msg
+}
+
+func TestWriteValueStruct(t *testing.T) {
+	msg := getSingleEntryTestMsg()
+	sm := toStructMsg(msg)
+	sm.MyMap["m1"].Ignored = "ignored"
+	sm.MyMap["m1"].Skipped = "skipped"
+
+	buf := NewBuffer(256)
+	if err := WriteValue(buf, msg); err != nil {
+		t.Fatalf("write message err:%v", err)
+	}
+	sbuf := NewBuffer(256)
+	if err := WriteValue(sbuf, sm); err != nil {
+		t.Fatalf("write struct err:%v", err)
+	}
+	if !reflect.DeepEqual(buf.Bytes(), sbuf.Bytes()) {
+		t.Errorf("struct bytes not equal with generated message. expect %v, real %v", buf.Bytes(), sbuf.Bytes())
+	}
+
+	// read generated bytes into struct
+	var result testStructMsg
+	if _, err := ReadValue(CreateBuffer(buf.Bytes()), &result); err != nil {
+		t.Fatalf("read struct err:%v", err)
+	}
+	sm.MyMap["m1"].Ignored = ""
+	sm.MyMap["m1"].Skipped = ""
+	if !reflect.DeepEqual(&result, sm) {
+		t.Errorf("wrong result. expect %v, real %v", sm, result)
+	}
+
+	// read by type
+	ret, err := ReadValue(CreateBuffer(sbuf.Bytes()), reflect.TypeOf(sm))
+	if err != nil {
+		t.Fatalf("read struct by type err:%v", err)
+	}
+	if !reflect.DeepEqual(ret, sm) {
+		t.Errorf("wrong result. expect %v, real %v", sm, ret)
+	}
+
+	// read struct bytes into generated message
+	var tm TestMsg
+	if _, err = ReadValue(CreateBuffer(sbuf.Bytes()), &tm); err != nil {
+		t.Fatalf("read message err:%v", err)
+	}
+	if !reflect.DeepEqual(&tm, msg) {
+		t.Errorf("wrong result. expect %v, real %v", msg, tm)
+	}
+
+	// struct array without pointer
+	a := []testStructSubMsg{*toStructSubMsg(getTestSubMsg()), *toStructSubMsg(getTestSubMsgByInt(3))}
+	abuf := NewBuffer(256)
+	if err = WriteValue(abuf, a); err != nil {
+		t.Fatalf("write struct array err:%v", err)
+	}
+	ret, err = ReadValue(CreateBuffer(abuf.Bytes()), reflect.TypeOf(a))
+	if err != nil {
+		t.Fatalf("read struct array err:%v", err)
+	}
+	if !reflect.DeepEqual(ret, a) {
+		t.Errorf("wrong result. expect %v, real %v", a, ret)
+	}
+
+	// slices of basic types are read and written by the typed functions, the named types by reflection
+	type names []string
+	type sliceMsg struct {
+		S  []string           `breeze:"1"`
+		I  []int64            `breeze:"2"`
+		N  names              `breeze:"3"`
+		M  map[string][]int32 `breeze:"4"`
+		I2 []int32            `breeze:"5"`
+	}
+	sl := &sliceMsg{S: []string{"a", "b"}, I: []int64{1, -300}, N: names{"n"}, M: map[string][]int32{"x": {1}, "y": nil}}
+	slbuf := NewBuffer(64)
+	WriteValue(slbuf, sl)
+	var slr sliceMsg
+	if _, err = ReadValue(CreateBuffer(slbuf.Bytes()), &slr); err != nil || !reflect.DeepEqual(&slr, sl) {
+		t.Errorf("wrong slice struct. err:%v, expect %v, real %v", err, sl, slr)
+	}
+	// the elements are converted if the element type is not the same
+	slbuf = NewBuffer(64)
+	WriteMessageType(slbuf, "breeze.sliceMsg")
+	WriteMessageWithoutType(slbuf, func(buf *Buffer) {
+		WriteField(buf, 5, []int64{2, 3})
+	})
+	slr = sliceMsg{}
+	if _, err = ReadValue(CreateBuffer(slbuf.Bytes()), &slr); err != nil || !reflect.DeepEqual(slr.I2, []int32{2, 3}) {
+		t.Errorf("wrong converted slice. err:%v, real %v", err, slr.I2)
+	}
+}
+
+func TestStructTag(t *testing.T) {
+	type defaultMsg struct {
+		A int32             `breeze:"1"`
+		B string            `breeze:"2,name=bName,omitempty"`
+		C map[string]string `breeze:"3"`
+	}
+	// field without omitempty will be written even if it is default value
+	buf := NewBuffer(32)
+	if err := WriteValue(buf, defaultMsg{}); err != nil {
+		t.Fatalf("write struct err:%v", err)
+	}
+	expect := NewBuffer(32)
+	WriteMessageType(expect, "breeze.defaultMsg")
+	WriteMessageWithoutType(expect, func(buf *Buffer) {
+		WriteField(buf, 1, int32(0))
+	})
+	if !reflect.DeepEqual(buf.Bytes(), expect.Bytes()) {
+		t.Errorf("wrong struct bytes. expect %v, real %v", expect.Bytes(), buf.Bytes())
+	}
+
+	info, err := getStructInfo(reflect.TypeOf(defaultMsg{}))
+	if err != nil {
+		t.Fatalf("get struct info err:%v", err)
+	}
+	if info.schema.GetFieldByName("bName") == nil || info.schema.GetFieldByIndex(1).Name != "a" {
+		t.Errorf("wrong struct schema: %+v", info.schema)
+	}
+	if info.schema.GetFieldByIndex(3).Type != "map<string, string>" {
+		t.Errorf("wrong field type: %s", info.schema.GetFieldByIndex(3).Type)
+	}
+
+	type wrongMsg struct {
+		A int32 `breeze:"1"`
+		B int32 `breeze:"1"`
+	}
+	if err = WriteValue(NewBuffer(32), wrongMsg{}); err == nil {
+		t.Errorf("duplicate field index should fail")
+	}
+}
+
+type testTreeNode struct {
+	Value    string                   `breeze:"1"`
+	Children []*testTreeNode          `breeze:"2,omitempty"`
+	Named    map[string]*testTreeNode `breeze:"3,omitempty"`
+}
+
+func TestCodecRecursiveType(t *testing.T) {
+	leaf := &testTreeNode{Value: "leaf"}
+	root := &testTreeNode{Value: "root", Children: []*testTreeNode{leaf, {Value: "leaf2"}}, Named: map[string]*testTreeNode{"l": leaf}}
+	for i := 0; i < 2; i++ { // the second time uses cached codec
+		buf := NewBuffer(64)
+		if err := WriteValue(buf, root); err != nil {
+			t.Fatalf("write recursive type err:%v", err)
+		}
+		ret, err := ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(root))
+		if err != nil {
+			t.Fatalf("read recursive type err:%v", err)
+		}
+		if !reflect.DeepEqual(ret, root) {
+			t.Errorf("wrong result. expect %v, real %v", root, ret)
+		}
+	}
+	c, ok := codecs.Load(reflect.TypeOf(root))
+	if !ok || c.(*codec) != getCodec(reflect.TypeOf(root)) {
+		t.Errorf("codec not cached")
+	}
+}
+
+func TestWriteCircularReference(t *testing.T) {
+	node := &testTreeNode{Value: "root"}
+	node.Children = []*testTreeNode{{Value: "child"}, node}
+	msg := &GenericMessage{Name: "motan.Circular"}
+	msg.PutField(1, msg)
+	m := map[string]interface{}{"a": 1}
+	m["self"] = m
+	a := []interface{}{"a", nil}
+	a[1] = a
+	buf := NewBuffer(64)
+	for _, v := range []interface{}{node, msg, m, a} {
+		buf.Reset()
+		if err := WriteValue(buf, v); err != ErrCircularReference {
+			t.Errorf("should return ErrCircularReference. v:%T, err:%v", v, err)
+		}
+		// buffer can be used after error
+		buf.Reset()
+		if err := WriteValue(buf, getTestMsg()); err != nil {
+			t.Errorf("write after circular reference fail. err:%v", err)
+		}
+	}
+	// sub slices with same data pointer are not circular
+	a = []interface{}{"s", nil}
+	a[1] = a[:1]
+	buf.Reset()
+	if err := WriteValue(buf, a); err != nil {
+		t.Errorf("write sub slice fail. err:%v", err)
+	}
+}
+
+// testAliasMsg has pointer fields to its own fields, which have the same address as the struct
+type testAliasMsg struct {
+	N int32  `breeze:"1"`
+	P *int32 `breeze:"2"`
+}
+
+type testAliasStructMsg struct {
+	S testAliasMsg  `breeze:"1"`
+	P *testAliasMsg `breeze:"2"`
+}
+
+func TestWriteAliasPointer(t *testing.T) {
+	a := &testAliasMsg{N: 3}
+	a.P = &a.N
+	s := &testAliasStructMsg{S: testAliasMsg{N: 5}}
+	s.P = &s.S
+	for _, v := range []interface{}{a, s} {
+		buf := NewBuffer(64)
+		if err := WriteValue(buf, v); err != nil {
+			t.Errorf("write alias pointer fail. v:%T, err:%v", v, err)
+			continue
+		}
+		ret, err := ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(v))
+		if err != nil {
+			t.Errorf("read alias pointer fail. v:%T, err:%v", v, err)
+		} else if !reflect.DeepEqual(ret, v) {
+			t.Errorf("wrong result. expect %v, real %v", v, ret)
+		}
+	}
+}
+
+func TestWriteMaxDepth(t *testing.T) {
+	node := &testTreeNode{Value: "leaf"}
+	for i := 0; i < 10; i++ {
+		node = &testTreeNode{Value: "node", Named: map[string]*testTreeNode{"c": node}}
+	}
+	msg := &GenericMessage{Name: "motan.Depth"}
+	for i := 0; i < 10; i++ {
+		parent := &GenericMessage{Name: "motan.Depth"}
+		parent.PutField(1, msg)
+		msg = parent
+	}
+	for _, v := range []interface{}{node, msg} {
+		buf := NewBuffer(64)
+		if err := WriteValue(buf, v); err != nil {
+			t.Errorf("write with default max depth fail. v:%T, err:%v", v, err)
+		}
+		buf.Reset()
+		buf.SetMaxWriteDepth(10)
+		if err := WriteValue(buf, v); err != ErrMaxDepth {
+			t.Errorf("should return ErrMaxDepth. v:%T, err:%v", v, err)
+		}
+		buf.Reset()
+		buf.SetMaxWriteDepth(30)
+		if err := WriteValue(buf, v); err != nil {
+			t.Errorf("write with max depth 30 fail. v:%T, err:%v", v, err)
+		}
+	}
+}
+
+// testBadElemMsg writes an unsupported value in the callback of packed array
+type testBadElemMsg struct {
+	nested bool // write a testBadElemMsg by WriteMessageField without WriteMessageWithoutType
+}
+
+func (m *testBadElemMsg) WriteTo(buf *Buffer) error {
+	if m.nested {
+		WriteMessageField(buf, 1, &testBadElemMsg{})
+		return nil
+	}
+	return WriteMessageWithoutType(buf, func(buf *Buffer) {
+		WriteArrayField(buf, 1, 1, func(buf *Buffer) {
+			WriteField(buf, 1, make(chan int))
+		})
+	})
+}
+
+func (m *testBadElemMsg) ReadFrom(buf *Buffer) error {
+	return ReadMessageField(buf, skipField)
+}
+
+func (m *testBadElemMsg) GetName() string {
+	return "motan.BadElem"
+}
+
+func (m *testBadElemMsg) GetAlias() string {
+	return ""
+}
+
+func (m *testBadElemMsg) GetSchema() *Schema {
+	return nil
+}
+
+func TestWriteAfterNestedError(t *testing.T) {
+	buf := NewBuffer(64)
+	if err := WriteValue(buf, &testBadElemMsg{}); err == nil {
+		t.Errorf("write unsupported value in packed array should fail")
+	}
+	if len(buf.writeRefs) != 0 {
+		t.Errorf("write refs should be cleared after error. refs:%v", buf.writeRefs)
+	}
+	// the buffer can be written again without Reset
+	if err := WriteValue(buf, &TestSubMsg{MyInt: 3}); err != nil {
+		t.Errorf("write after nested error fail. err:%v", err)
+	}
+	// WriteMessageField in WriteTo without WriteMessageWithoutType
+	if err := WriteValue(buf, &testBadElemMsg{nested: true}); err == nil {
+		t.Errorf("write bad nested message should fail")
+	}
+	if err := WriteValue(buf, &TestSubMsg{MyInt: 3}); err != nil {
+		t.Errorf("write after nested error fail. err:%v", err)
+	}
+}
+
+// testPanicMsg panics in WriteTo
+type testPanicMsg struct {
+	testBadElemMsg
+}
+
+func (m *testPanicMsg) WriteTo(buf *Buffer) error {
+	panic("write panic")
+}
+
+func TestWriteAfterPanic(t *testing.T) {
+	buf := NewBuffer(64)
+	msg := &testPanicMsg{}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("write panic message should panic")
+			}
+		}()
+		WriteValue(buf, msg)
+	}()
+	if len(buf.writeRefs) != 0 {
+		t.Errorf("write refs should be cleared after panic. refs:%v", buf.writeRefs)
+	}
+	if err := WriteValue(buf, &TestSubMsg{MyInt: 3}); err != nil {
+		t.Errorf("write after panic fail. err:%v", err)
+	}
+}
+
+func TestReadMalformedData(t *testing.T) {
+	// packed type missing
+	buf := CreateBuffer([]byte{})
+	if err := ReadPacked(buf, 1, true, func(buf *Buffer) error { return nil }); err == nil {
+		t.Errorf("read packed without type should fail")
+	}
+	// declared message size is larger than the remain bytes
+	buf = CreateBuffer([]byte{0, 0, 0, 100, 1, 0x51})
+	if err := ReadMessageField(buf, func(buf *Buffer, index int) error { return nil }); err != ErrNotEnough {
+		t.Errorf("should return ErrNotEnough. err:%v", err)
+	}
+	// field can not be read beyond the message
+	buf = CreateBuffer([]byte{0, 0, 0, 2, 1, StringType, 3, 'a', 'b', 'c'})
+	err := ReadMessageField(buf, func(buf *Buffer, index int) error {
+		_, err := ReadValue(buf, nil)
+		return err
+	})
+	if err != ErrWrongSize || buf.Remain() != 4 {
+		t.Errorf("should return ErrWrongSize. err:%v, remain:%d", err, buf.Remain())
+	}
+	// unhashable map key
+	data := []byte{MapType, 1, ArrayType, 1, 0x01, 'a', 0x01, 'b'}
+	for _, v := range []interface{}{nil, reflect.TypeOf(map[interface{}]string{})} {
+		if _, err = ReadValue(CreateBuffer(data), v); err == nil {
+			t.Errorf("unhashable map key should fail. v:%v", v)
+		}
+	}
+	// element type of packed array is missing or wrong
+	readers := map[string]func(buf *Buffer) error{
+		"string": func(buf *Buffer) error { _, err := ReadStringArray(buf, true); return err },
+		"int32":  func(buf *Buffer) error { _, err := ReadInt32Array(buf, true); return err },
+		"int64":  func(buf *Buffer) error { _, err := ReadInt64Array(buf, true); return err },
+	}
+	for name, read := range readers {
+		if err = read(CreateBuffer([]byte{PackedArrayType, 2})); err == nil {
+			t.Errorf("read %s array without element type should fail", name)
+		}
+		if _, ok := read(CreateBuffer([]byte{PackedArrayType, 2, BytesType, 0, 0, 0, 1})).(*TypeMismatchError); !ok {
+			t.Errorf("read %s array with wrong element type should be type mismatch", name)
+		}
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	msg := getTestMsg()
+	msg.MyMap = nil
+	msg.MyArray = nil
+	msg.SubMsg = getTestSubMsg()
+	buf := NewBuffer(256)
+	buf.SetWriteSchema(true)
+	if err := WriteValue(buf, msg); err != nil {
+		t.Fatalf("write message err:%v", err)
+	}
+	noSchema := NewBuffer(256)
+	WriteValue(noSchema, msg)
+	if buf.Len() <= noSchema.Len() {
+		t.Errorf("schema not written. len with schema:%d, without schema:%d", buf.Len(), noSchema.Len())
+	}
+
+	// read as GenericMessage
+	rbuf := CreateBuffer(buf.Bytes())
+	r, err := ReadValue(rbuf, nil)
+	if err != nil {
+		t.Fatalf("read message err:%v", err)
+	}
+	gm := r.(*GenericMessage)
+	v, err := gm.GetFieldByName("myString")
+	if err != nil || v != msg.MyString {
+		t.Errorf("wrong field by name. expect:%v, real:%v, err:%v", msg.MyString, v, err)
+	}
+	sub, _ := gm.GetFieldByName("subMsg")
+	v, err = sub.(*GenericMessage).GetFieldByName("myInt64")
+	if err != nil || v != msg.SubMsg.MyInt64 {
+		t.Errorf("wrong sub message field by name. expect:%v, real:%v, err:%v", msg.SubMsg.MyInt64, v, err)
+	}
+	schema := rbuf.GetContext().GetSchema(testMsgBreezeSchema.Name)
+	if schema == nil || !reflect.DeepEqual(schema.GetFields(), testMsgBreezeSchema.GetFields()) {
+		t.Errorf("wrong schema in context. expect:%v, real:%v", testMsgBreezeSchema, schema)
+	}
+
+	// read type with schema and message type ref
+	rbuf = CreateBuffer(buf.Bytes())
+	tp, name, err := ReadType(rbuf)
+	if err != nil || tp != MessageType || name != testMsgBreezeSchema.Name || rbuf.GetContext().GetSchema(name) == nil {
+		t.Errorf("wrong type. err:%v, type:%s, name:%s", err, TypeName(tp), name)
+	}
+	tp, name, err = ReadType(CreateBuffer([]byte{RefMessageType + 1}))
+	if err != nil || tp != MessageType || name != "" {
+		t.Errorf("message type ref should not be found. err:%v, type:%s, name:%s", err, TypeName(tp), name)
+	}
+
+	// read as concrete message
+	var result TestMsg
+	if _, err = ReadValue(CreateBuffer(buf.Bytes()), &result); err != nil {
+		t.Fatalf("read message err:%v", err)
+	}
+	if !reflect.DeepEqual(&result, msg) {
+		t.Errorf("wrong result. expect %v, real %v", msg, result)
+	}
+
+	// schema only written at the first time
+	buf.Reset()
+	WriteValue(buf, msg)
+	noSchema.Reset()
+	WriteValue(noSchema, msg)
+	if buf.Len() != noSchema.Len() {
+		t.Errorf("schema should not be written again. expect len:%d, real len:%d", noSchema.Len(), buf.Len())
+	}
+
+	// struct schema
+	buf = NewBuffer(256)
+	buf.SetWriteSchema(true)
+	WriteValue(buf, toStructMsg(msg))
+	r, err = ReadValue(CreateBuffer(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("read struct message err:%v", err)
+	}
+	v, err = r.(*GenericMessage).GetFieldByName("myInt")
+	if err != nil || v != msg.MyInt {
+		t.Errorf("wrong field by name. expect:%v, real:%v, err:%v", msg.MyInt, v, err)
+	}
+}
+
+type testUnsignedMsg struct {
+	_   struct{}          `breeze:"name=testUnsignedMsg"`
+	U16 uint16            `breeze:"1"`
+	U32 uint32            `breeze:"2"`
+	U   uint              `breeze:"3"`
+	U64 uint64            `breeze:"4"`
+	IDs []uint64          `breeze:"5"`
+	M   map[uint32]uint16 `breeze:"6"`
+}
+
+func TestWriteUnsigned(t *testing.T) {
+	msg := &testUnsignedMsg{U16: math.MaxUint16, U32: math.MaxUint32, U: math.MaxInt64, U64: math.MaxInt64,
+		IDs: []uint64{1, math.MaxUint32 + 1, math.MaxInt64}, M: map[uint32]uint16{math.MaxUint32: math.MaxUint16}}
+	buf := NewBuffer(128)
+	if err := WriteValue(buf, msg); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	result := &testUnsignedMsg{}
+	if _, err := ReadValue(CreateBuffer(buf.Bytes()), result); err != nil || !reflect.DeepEqual(msg, result) {
+		t.Errorf("wrong unsigned message. err:%v, expect:%+v, real:%+v", err, msg, result)
+	}
+	// unsigned integers are written as wider signed integers
+	v, err := ReadValue(CreateBuffer(buf.Bytes()), nil)
+	g, _ := v.(*GenericMessage)
+	if err != nil || g == nil || g.GetFieldByIndex(1) != int32(math.MaxUint16) || g.GetFieldByIndex(2) != int64(math.MaxUint32) ||
+		!reflect.DeepEqual(g.GetFieldByIndex(5), []interface{}{int64(1), int64(math.MaxUint32 + 1), int64(math.MaxInt64)}) {
+		t.Errorf("wrong generic message. err:%v, message:%+v", err, v)
+	}
+	info, _ := getStructInfo(reflect.TypeOf(*msg))
+	for index, tp := range map[int]string{1: "int32", 2: "int64", 3: "int64", 4: "int64", 5: "array<int64>", 6: "map<int64, int32>"} {
+		if f := info.schema.GetFieldByIndex(index); f == nil || f.Type != tp {
+			t.Errorf("wrong field type of %d. expect:%s, real:%+v", index, tp, f)
+		}
+	}
+
+	// values over math.MaxInt64 can not be written
+	for _, v := range []interface{}{uint64(math.MaxUint64), []uint64{1, math.MaxInt64 + 1}, &testUnsignedMsg{U: math.MaxInt64 + 1}} {
+		buf = NewBuffer(64)
+		err = WriteValue(buf, v)
+		if !errors.Is(err, ErrOverflow) || !strings.Contains(err.Error(), "overflows int64") {
+			t.Errorf("should return overflow error. value:%v, err:%v", v, err)
+		}
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+	m := make(map[string]int32, 64)
+	im := make(map[interface{}]interface{}, 64)
+	g := &GenericMessage{Name: "canonical"}
+	for i := 0; i < 64; i++ {
+		m["k"+strconv.Itoa(i)] = int32(i)
+		im[i] = "v"
+		im["k"+strconv.Itoa(i)] = int64(i)
+		g.PutField(i, []byte("f"))
+	}
+	im[true] = 1.5
+	values := []interface{}{m, im, g, map[float64][]string{1.5: {"a"}, -2: {}, 0: {"b"}}, &testStructSubMsg{MyMap1: map[string][]byte{"x": {1}, "a": {2}}}}
+	for _, v := range values {
+		expect := NewBuffer(256)
+		expect.SetCanonical(true)
+		if err := WriteValue(expect, v); err != nil {
+			t.Fatalf("write fail. err:%v", err)
+		}
+		for i := 0; i < 10; i++ {
+			buf := NewBuffer(256)
+			buf.SetCanonical(true)
+			WriteValue(buf, v)
+			if !bytes.Equal(expect.Bytes(), buf.Bytes()) {
+				t.Fatalf("canonical bytes should be identical. value:%v", v)
+			}
+		}
+	}
+
+	// messages with map fields
+	msg := getTestMsg()
+	msg.MyMap["m2"] = getTestSubMsgByInt(2)
+	msg.MyMap["m0"] = getTestSubMsgByInt(0)
+	expect := NewBuffer(1024)
+	expect.SetCanonical(true)
+	WriteValue(expect, msg)
+	for i := 0; i < 10; i++ {
+		buf := NewBuffer(1024)
+		buf.SetCanonical(true)
+		WriteValue(buf, getTestMsg())
+		other := NewBuffer(1024)
+		other.SetCanonical(true)
+		WriteValue(other, getTestMsg())
+		if !bytes.Equal(buf.Bytes(), other.Bytes()) {
+			t.Fatalf("canonical bytes of message should be identical")
+		}
+		buf = NewBuffer(1024)
+		buf.SetCanonical(true)
+		WriteValue(buf, msg)
+		if !bytes.Equal(expect.Bytes(), buf.Bytes()) {
+			t.Fatalf("canonical bytes of message with multiple map entries should be identical")
+		}
+	}
+
+	// keys in ascending order
+	buf := NewBuffer(64)
+	buf.SetCanonical(true)
+	WriteValue(buf, map[int32]bool{3: true, -1: false, 2: true})
+	if !bytes.Equal(buf.Bytes(), []byte{PackedMapType, 3, Int32Type, TrueType, 0x01, FalseType, 0x04, TrueType, 0x06, TrueType}) {
+		t.Errorf("wrong canonical map: %x", buf.Bytes())
+	}
+	buf = NewBuffer(64)
+	buf.SetCanonical(true)
+	WriteStringInt32MapEntries(buf, map[string]int32{"b": 1, "a": 2})
+	if !bytes.Equal(buf.Bytes(), []byte{StringType, Int32Type, 1, 'a', 0x04, 1, 'b', 0x02}) {
+		t.Errorf("wrong canonical map entries: %x", buf.Bytes())
+	}
+	g = &GenericMessage{Name: "g"}
+	g.PutField(3, int32(3))
+	g.PutField(1, int32(1))
+	g.PutField(2, int32(2))
+	buf = NewBuffer(64)
+	buf.SetCanonical(true)
+	WriteValue(buf, g)
+	if !bytes.Equal(buf.Bytes()[7:], []byte{1, 0x51, 2, 0x52, 3, 0x53}) {
+		t.Errorf("wrong canonical generic message: %x", buf.Bytes())
+	}
+}
+
+type testOptionalMsg struct {
+	I *int32            `breeze:"1"`
+	S *string           `breeze:"2"`
+	B *bool             `breeze:"3"`
+	F *float64          `breeze:"4"`
+	U *uint16           `breeze:"5"`
+	N *int64            `breeze:"6"`
+	M map[string]*int32 `breeze:"7"`
+}
+
+func TestWriteOptional(t *testing.T) {
+	// field writers write default values but skip nil
+	i, s, b := int32(0), "", false
+	buf := NewBuffer(64)
+	WriteOptionalInt32Field(buf, 1, &i)
+	WriteOptionalStringField(buf, 2, &s)
+	WriteOptionalBoolField(buf, 3, &b)
+	WriteOptionalInt64Field(buf, 4, nil)
+	WriteOptionalFloat64Field(buf, 5, nil)
+	if !bytes.Equal(buf.Bytes(), []byte{1, 0x50, 2, 0x00, 3, FalseType}) {
+		t.Errorf("wrong optional fields: %x", buf.Bytes())
+	}
+
+	// readers set nil for null
+	buf = CreateBuffer([]byte{0x50, NullType, 0x00, NullType, FalseType})
+	pi := new(int32)
+	*pi = 5
+	var ps *string
+	var pb *bool
+	if err := ReadOptionalInt32(buf, &pi); err != nil || pi == nil || *pi != 0 {
+		t.Errorf("wrong optional int32. err:%v, value:%v", err, pi)
+	}
+	if err := ReadOptionalInt32(buf, &pi); err != nil || pi != nil {
+		t.Errorf("optional int32 should be nil. err:%v, value:%v", err, pi)
+	}
+	if err := ReadOptionalString(buf, &ps); err != nil || ps == nil || *ps != "" {
+		t.Errorf("wrong optional string. err:%v, value:%v", err, ps)
+	}
+	if err := ReadOptionalBool(buf, &pb); err != nil || pb != nil {
+		t.Errorf("optional bool should be nil. err:%v, value:%v", err, pb)
+	}
+	if err := ReadOptionalBool(buf, &pb); err != nil || pb == nil || *pb {
+		t.Errorf("wrong optional bool. err:%v, value:%v", err, pb)
+	}
+	if err := ReadOptionalInt64(buf, new(*int64)); err != io.EOF {
+		t.Errorf("should return EOF. err:%v", err)
+	}
+
+	// pointer fields of struct, nil is not written and default values are written
+	f, u := 1.5, uint16(0)
+	msg := &testOptionalMsg{I: &i, S: &s, B: &b, F: &f, U: &u, M: map[string]*int32{"a": &i}}
+	buf = NewBuffer(128)
+	if err := WriteValue(buf, msg); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	n := int64(3)
+	result := &testOptionalMsg{N: &n}
+	if _, err := ReadValue(CreateBuffer(buf.Bytes()), result); err != nil || result.N != &n { // absent field is not changed
+		t.Errorf("absent field should not be changed. err:%v, value:%v", err, result.N)
+	}
+	result.N = nil
+	if !reflect.DeepEqual(msg, result) {
+		t.Errorf("wrong optional message. expect:%+v, real:%+v", msg, result)
+	}
+	g, err := ReadValue(CreateBuffer(buf.Bytes()), nil)
+	if err != nil || len(g.(*GenericMessage).fields) != 6 || g.(*GenericMessage).GetFieldByIndex(1) != int32(0) {
+		t.Errorf("wrong generic message. err:%v, message:%+v", err, g)
+	}
+
+	// nil pointers and nil interfaces are written as NullType
+	buf = NewBuffer(64)
+	if err := WriteValue(buf, (*int32)(nil)); err != nil || !bytes.Equal(buf.Bytes(), []byte{NullType}) {
+		t.Errorf("nil pointer should be written as null. err:%v, bytes:%x", err, buf.Bytes())
+	}
+	if v, err := ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(pi)); err != nil || v != nil {
+		t.Errorf("wrong nil pointer. err:%v, value:%v", err, v)
+	}
+	pi = &i
+	if _, err := ReadValue(CreateBuffer(buf.Bytes()), &pi); err != nil || pi != nil {
+		t.Errorf("pointer should be set to nil. err:%v, value:%v", err, pi)
+	}
+	if v, err := ReadValue(CreateBuffer([]byte{0x5c}), &pi); err != nil || pi == nil || *pi != 12 || v != int32(12) {
+		t.Errorf("wrong pointer value. err:%v, value:%v", err, v)
+	}
+	if v, err := ReadValue(CreateBuffer([]byte{0x5c}), reflect.TypeOf(pi)); err != nil || *(v.(*int32)) != 12 {
+		t.Errorf("wrong pointer value. err:%v, value:%v", err, v)
+	}
+	values := []interface{}{[]interface{}{nil, "a"}, map[string]interface{}{"a": nil, "b": int64(1)}}
+	for _, v := range values {
+		buf = NewBuffer(64)
+		if err := WriteValue(buf, v); err != nil {
+			t.Fatalf("write fail. err:%v", err)
+		}
+		result, err := ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(v))
+		if err != nil || !reflect.DeepEqual(v, result) {
+			t.Errorf("wrong nil elements. err:%v, expect:%v, real:%v", err, v, result)
+		}
+	}
+	// nil can not be written into packed array
+	if err := WriteValue(NewBuffer(64), []*int32{&i, nil}); err == nil {
+		t.Errorf("nil element of packed array should not be written")
+	}
+}
+
+func TestRegisterMessage(t *testing.T) {
+	RegisterMessage(&TestMsg{})
+	RegisterMessage(&TestSubMsg{})
+	RegisterEnum(MyEnumE1)
+	defer func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		delete(messageTypes, testMsgBreezeSchema.Name)
+		delete(messageTypes, testSubMsgBreezeSchema.Name)
+		delete(enums, myEnumBreezeSchema.Name)
+	}()
+
+	msg := getTestMsg()
+	buf := NewBuffer(256)
+	WriteValue(buf, msg)
+	r, err := ReadValue(CreateBuffer(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("read message err:%v", err)
+	}
+	if !reflect.DeepEqual(r, msg) {
+		t.Errorf("wrong result. expect %v, real %v", msg, r)
+	}
+
+	// interface{} elements of map and array
+	e := MyEnumE2
+	m := map[string]interface{}{"sub": getTestSubMsg(), "enum": &e}
+	a := []interface{}{getTestSubMsg(), "str"}
+	for _, v := range []interface{}{m, a} {
+		buf = NewBuffer(256)
+		if err = WriteValue(buf, v); err != nil {
+			t.Fatalf("write value err:%v", err)
+		}
+		r, err = ReadValue(CreateBuffer(buf.Bytes()), reflect.TypeOf(v))
+		if err != nil {
+			t.Fatalf("read value err:%v", err)
+		}
+		if !reflect.DeepEqual(r, v) {
+			t.Errorf("wrong result. expect %v, real %v", v, r)
+		}
+	}
+
+	// registered schema will be written even if the message type is written by name
+	buf = NewBuffer(256)
+	buf.SetWriteSchema(true)
+	WriteMessageType(buf, testSubMsgBreezeSchema.Name)
+	rbuf := CreateBuffer(buf.Bytes())
+	readType(rbuf)
+	if rbuf.GetContext().GetSchema(testSubMsgBreezeSchema.Name) == nil {
+		t.Errorf("registered schema not written")
+	}
+}
+
 func BenchmarkWriteMessage(b *testing.B) {
 	testmsg := GetBenchData(100)
 	buf := NewBuffer(5000)
@@ -602,3 +1399,49 @@ func BenchmarkGen(b *testing.B) {
 		GetBenchData(100)
 	}
 }
+
+func BenchmarkWriteLargeMessage(b *testing.B) {
+	benchmarkWrite(b, GetBenchData(1000))
+}
+
+func BenchmarkWriteLargeStruct(b *testing.B) {
+	benchmarkWrite(b, toStructMsg(GetBenchData(1000)))
+}
+
+func BenchmarkReadLargeMessage(b *testing.B) {
+	benchmarkRead(b, GetBenchData(1000), &TestMsg{})
+}
+
+func BenchmarkReadLargeStruct(b *testing.B) {
+	benchmarkRead(b, toStructMsg(GetBenchData(1000)), &testStructMsg{})
+}
+
+func benchmarkWrite(b *testing.B, v interface{}) {
+	buf := NewBuffer(5000)
+	err := WriteValue(buf, v)
+	if err != nil {
+		fmt.Printf("err:%v\n", err)
+		b.Fail()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		WriteValue(buf, v)
+	}
+}
+
+func benchmarkRead(b *testing.B, v interface{}, result interface{}) {
+	buf := NewBuffer(5000)
+	WriteValue(buf, v)
+	rBuffer := CreateBuffer(buf.Bytes())
+	ret, err := ReadValue(rBuffer, result)
+	if ret == nil || err != nil {
+		fmt.Printf("ret:%v, err:%v\n", ret, err)
+		b.Fail()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rBuffer.SetRPos(0)
+		ReadValue(rBuffer, result)
+	}
+}
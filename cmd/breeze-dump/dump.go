@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+// max length of strings and bytes in dumping, the longer ones are truncated
+const maxValueLength = 64
+
+// max nesting depth of dumping, it protects from malformed data
+const maxDepth = 1000
+
+// DumpError is a error found at offset in dumping
+type DumpError struct {
+	Offset int
+	Err    error
+}
+
+func (e *DumpError) Error() string {
+	return "offset " + strconv.Itoa(e.Offset) + ": " + e.Err.Error()
+}
+
+// dumper print breeze values as an annotated tree. each line is a node with offset, type, length and value
+type dumper struct {
+	w       io.Writer
+	buf     *breeze.Buffer
+	schemas map[string]*breeze.Schema // loaded schemas by name and alias
+	depth   int
+}
+
+// dump print all values in buffer
+func (d *dumper) dump() error {
+	for d.buf.Remain() > 0 {
+		if err := d.value(""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value print a value with type
+func (d *dumper) value(label string) error {
+	pos, tp, name, err := d.readType()
+	if err != nil {
+		return err
+	}
+	return d.valueOfType(pos, label, tp, name, false)
+}
+
+// readType read a type by breeze.ReadType. the schemas before message type are put into context by ReadType and printed
+// before the message, and the type byte of message type ref is kept for printing
+func (d *dumper) readType() (pos int, tp byte, name string, err error) {
+	pos = d.buf.GetRPos()
+	if d.buf.Remain() == 0 {
+		return pos, tp, name, d.error(pos, breeze.ErrNotEnough)
+	}
+	raw := d.buf.Bytes()[pos]
+	if tp, name, err = breeze.ReadType(d.buf); err != nil {
+		return pos, tp, name, d.error(pos, err)
+	}
+	if raw == breeze.SchemaType {
+		d.schema(pos, name)
+	} else if tp == breeze.MessageType {
+		tp = raw
+	}
+	return pos, tp, name, nil
+}
+
+// schema print the schema of message from context, the schema has been read by breeze.ReadType. the fields are printed
+// in the order of index without offsets
+func (d *dumper) schema(pos int, name string) {
+	schema := d.buf.GetContext().GetSchema(name)
+	if schema == nil {
+		return
+	}
+	fields := schema.GetFields()
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Index < fields[j].Index })
+	detail := schema.Name
+	if schema.Alias != "" {
+		detail += " alias=" + schema.Alias
+	}
+	d.line(pos, "", breeze.SchemaType, false, detail+" fields="+strconv.Itoa(len(fields)))
+	d.depth++
+	defer func() { d.depth-- }()
+	for _, field := range fields {
+		fmt.Fprintf(d.w, "%8s  %s%d %s: %s\n", "", d.indent(), field.Index, field.Name, field.Type)
+	}
+}
+
+// valueOfType print a value which type is tp. the elements of packed map and packed array are read without type
+func (d *dumper) valueOfType(pos int, label string, tp byte, name string, packed bool) error {
+	var detail string
+	switch {
+	case tp <= breeze.DirectStringMaxType && !packed:
+		b, err := d.buf.Next(int(tp))
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = stringDetail(string(b))
+	case tp == breeze.StringType:
+		s, err := breeze.ReadStringWithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = stringDetail(s)
+	case tp >= breeze.DirectInt32MinType && tp < breeze.Int32Type && !packed:
+		detail = strconv.Itoa(int(tp) - int(breeze.Int32Zero))
+	case tp == breeze.Int32Type:
+		i, err := breeze.ReadInt32WithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = strconv.Itoa(int(i))
+	case tp >= breeze.DirectInt64MinType && tp < breeze.Int64Type && !packed:
+		detail = strconv.Itoa(int(tp) - int(breeze.Int64Zero))
+	case tp == breeze.Int64Type:
+		i, err := breeze.ReadInt64WithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = strconv.FormatInt(i, 10)
+	case tp == breeze.NullType:
+		detail = "null"
+	case tp == breeze.TrueType || tp == breeze.FalseType:
+		if packed { // the bool elements are true or false type bytes
+			b, err := breeze.ReadBoolWithoutType(d.buf)
+			if err != nil {
+				return d.error(pos, err)
+			}
+			tp = breeze.FalseType
+			if b {
+				tp = breeze.TrueType
+			}
+		}
+		detail = strconv.FormatBool(tp == breeze.TrueType)
+	case tp == breeze.ByteType:
+		b, err := d.buf.ReadByte()
+		if err != nil {
+			return d.error(pos, breeze.ErrNotEnough)
+		}
+		detail = strconv.Itoa(int(b))
+	case tp == breeze.BytesType:
+		b, err := breeze.ReadBytesWithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = "len=" + strconv.Itoa(len(b)) + " "
+		if len(b) > maxValueLength {
+			detail += hex.EncodeToString(b[:maxValueLength]) + "..."
+		} else {
+			detail += hex.EncodeToString(b)
+		}
+	case tp == breeze.Int16Type:
+		i, err := breeze.ReadInt16WithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = strconv.Itoa(int(i))
+	case tp == breeze.Float32Type:
+		f, err := breeze.ReadFloat32WithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = strconv.FormatFloat(float64(f), 'g', -1, 32)
+	case tp == breeze.Float64Type:
+		f, err := breeze.ReadFloat64WithoutType(d.buf)
+		if err != nil {
+			return d.error(pos, err)
+		}
+		detail = strconv.FormatFloat(f, 'g', -1, 64)
+	case tp == breeze.MapType || tp == breeze.PackedMapType:
+		return d.mapValue(pos, label, tp, packed)
+	case tp == breeze.ArrayType || tp == breeze.PackedArrayType:
+		return d.arrayValue(pos, label, tp, packed)
+	case tp >= breeze.MessageType:
+		return d.message(pos, label, tp, name, packed)
+	default:
+		return d.error(pos, fmt.Errorf("unknown type %s", breeze.TypeName(tp)))
+	}
+	d.line(pos, label, tp, packed, detail)
+	return nil
+}
+
+func (d *dumper) message(pos int, label string, tp byte, name string, packed bool) error {
+	if name == "" {
+		return d.error(pos, fmt.Errorf("message type %s not found in context", breeze.TypeName(tp)))
+	}
+	size, err := d.buf.ReadInt()
+	if err != nil {
+		return d.error(pos, err)
+	}
+	if size > d.buf.Remain() {
+		return d.error(pos, fmt.Errorf("message length %d exceeds remaining %d bytes", size, d.buf.Remain()))
+	}
+	d.line(pos, label, tp, packed, name+" len="+strconv.Itoa(size))
+	if err = d.enter(pos); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+	schema := d.buf.GetContext().GetSchema(name)
+	if schema == nil {
+		schema = d.schemas[name]
+	}
+	end := d.buf.GetRPos() + size
+	for d.buf.GetRPos() < end {
+		fpos := d.buf.GetRPos()
+		index, err := d.buf.ReadVarInt()
+		if err != nil {
+			return d.error(fpos, err)
+		}
+		label := strconv.FormatUint(index, 10) + ": "
+		if schema != nil {
+			if field := schema.GetFieldByIndex(int(index)); field != nil {
+				label = strconv.FormatUint(index, 10) + " " + field.Name + ": "
+			}
+		}
+		if err = d.value(label); err != nil {
+			return err
+		}
+	}
+	if d.buf.GetRPos() != end {
+		return d.error(pos, fmt.Errorf("fields of %s exceed message length %d", name, size))
+	}
+	return nil
+}
+
+func (d *dumper) mapValue(pos int, label string, tp byte, packed bool) error {
+	size, err := d.readSize(pos)
+	if err != nil {
+		return err
+	}
+	var ktp, vtp byte
+	var kn, vn string
+	detail := "size=" + strconv.Itoa(size)
+	if tp == breeze.PackedMapType && size > 0 {
+		if _, ktp, kn, err = d.readType(); err != nil {
+			return err
+		}
+		if _, vtp, vn, err = d.readType(); err != nil {
+			return err
+		}
+		detail += " " + typeDetail(ktp, kn) + " => " + typeDetail(vtp, vn)
+	}
+	d.line(pos, label, tp, packed, detail)
+	if err = d.enter(pos); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+	for i := 0; i < size; i++ {
+		if tp == breeze.PackedMapType {
+			if err = d.valueOfType(d.buf.GetRPos(), "key: ", ktp, kn, true); err != nil {
+				return err
+			}
+			if err = d.valueOfType(d.buf.GetRPos(), "value: ", vtp, vn, true); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = d.value("key: "); err != nil {
+			return err
+		}
+		if err = d.value("value: "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dumper) arrayValue(pos int, label string, tp byte, packed bool) error {
+	size, err := d.readSize(pos)
+	if err != nil {
+		return err
+	}
+	var etp byte
+	var en string
+	detail := "size=" + strconv.Itoa(size)
+	if tp == breeze.PackedArrayType && size > 0 {
+		if _, etp, en, err = d.readType(); err != nil {
+			return err
+		}
+		detail += " " + typeDetail(etp, en)
+	}
+	d.line(pos, label, tp, packed, detail)
+	if err = d.enter(pos); err != nil {
+		return err
+	}
+	defer func() { d.depth-- }()
+	for i := 0; i < size; i++ {
+		elemLabel := "[" + strconv.Itoa(i) + "]: "
+		if tp == breeze.PackedArrayType {
+			err = d.valueOfType(d.buf.GetRPos(), elemLabel, etp, en, true)
+		} else {
+			err = d.value(elemLabel)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSize read the size of map, array or schema fields. every element has one byte at least
+func (d *dumper) readSize(pos int) (int, error) {
+	size, err := d.buf.ReadVarInt()
+	if err != nil {
+		return 0, d.error(pos, err)
+	}
+	if size > uint64(d.buf.Remain()) {
+		return 0, d.error(pos, fmt.Errorf("size %d exceeds remaining %d bytes", size, d.buf.Remain()))
+	}
+	return int(size), nil
+}
+
+func (d *dumper) enter(pos int) error {
+	if d.depth >= maxDepth {
+		return d.error(pos, fmt.Errorf("nesting depth exceeds %d", maxDepth))
+	}
+	d.depth++
+	return nil
+}
+
+// line print a node. the type bytes of packed elements are not in data, so only the type names are printed
+func (d *dumper) line(pos int, label string, tp byte, packed bool, detail string) {
+	var typeName string
+	switch {
+	case !packed:
+		typeName = breeze.TypeName(tp) + fmt.Sprintf("(0x%02x)", tp)
+	case tp >= breeze.MessageType: // the message type of packed elements may be a ref
+		typeName = breeze.TypeName(breeze.MessageType)
+	default:
+		typeName = breeze.TypeName(tp)
+	}
+	fmt.Fprintf(d.w, "%8d  %s%s%s %s\n", pos, d.indent(), label, typeName, detail)
+}
+
+func (d *dumper) indent() string {
+	return strings.Repeat("  ", d.depth)
+}
+
+func (d *dumper) error(pos int, err error) error {
+	if err == io.EOF {
+		err = breeze.ErrNotEnough
+	}
+	return &DumpError{Offset: pos, Err: err}
+}
+
+func stringDetail(s string) string {
+	detail := "len=" + strconv.Itoa(len(s)) + " "
+	if len(s) > maxValueLength {
+		return detail + strconv.Quote(s[:maxValueLength]) + "..."
+	}
+	return detail + strconv.Quote(s)
+}
+
+// typeDetail get the element type of packed map or packed array, such as String and Message motan.TestSubMsg
+func typeDetail(tp byte, name string) string {
+	if tp >= breeze.MessageType {
+		return breeze.TypeName(breeze.MessageType) + " " + name
+	}
+	return breeze.TypeName(tp)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+func dumpBytes(data []byte, schemas map[string]*breeze.Schema) (string, error) {
+	var out bytes.Buffer
+	d := &dumper{w: &out, buf: breeze.CreateBuffer(data), schemas: schemas}
+	err := d.dump()
+	return out.String(), err
+}
+
+func TestDump(t *testing.T) {
+	buf := breeze.NewBuffer(64)
+	for _, v := range []interface{}{int32(1), "ab", []interface{}{true, int64(300)}, map[string]int32{"k": -1}, 1.5, []byte{1, 2}} {
+		breeze.WriteValue(buf, v)
+	}
+	expect := `       0  DirectInt32(0x51) 1
+       1  DirectString(0x02) len=2 "ab"
+       4  Array(0xda) size=2
+       6    [0]: True(0x9a) true
+       7    [1]: Int64(0x98) 300
+      10  PackedMap(0xdb) size=1 String => Int32
+      14    key: String len=1 "k"
+      16    value: Int32 -1
+      17  Float64(0xa0) 1.5
+      26  Bytes(0x9d) len=2 0102
+`
+	out, err := dumpBytes(buf.Bytes(), nil)
+	if err != nil || out != expect {
+		t.Errorf("wrong dump. err:%v, expect:\n%s\nreal:\n%s", err, expect, out)
+	}
+}
+
+func TestDumpMessage(t *testing.T) {
+	schemas, err := loadSchemas([]string{"../../idl/testdata/testmsg.breeze"})
+	if err != nil {
+		t.Fatalf("load schemas fail. err:%v", err)
+	}
+	buf := breeze.NewBuffer(256)
+	breeze.WriteValue(buf, &breeze.TestSubMsg{MyString: "a", MyInt: 5})
+	breeze.WriteValue(buf, []*breeze.TestSubMsg{{MyBool: true}})
+	expect := `       0  Message(0xde) motan.TestSubMsg len=8
+      23    1 myString: DirectString(0x01) len=1 "a"
+      26    2 myInt: DirectInt32(0x55) 5
+      28    6 myByte: Byte(0x9c) 0
+      30  PackedArray(0xdc) size=1 Message motan.TestSubMsg
+      33    [0]: Message motan.TestSubMsg len=5
+      38      6 myByte: Byte(0x9c) 0
+      41      11 myBool: True(0x9a) true
+`
+	out, err := dumpBytes(buf.Bytes(), schemas)
+	if err != nil || out != expect {
+		t.Errorf("wrong dump. err:%v, expect:\n%s\nreal:\n%s", err, expect, out)
+	}
+
+	// field names from the schemas in data
+	buf = breeze.NewBuffer(256)
+	buf.SetWriteSchema(true)
+	breeze.WriteValue(buf, &breeze.TestSubMsg{MyInt: 5})
+	breeze.WriteValue(buf, &breeze.TestSubMsg{MyInt: 6})
+	out, err = dumpBytes(buf.Bytes(), nil)
+	if err != nil || !strings.Contains(out, "Schema(0xdd) motan.TestSubMsg fields=11") || !strings.Contains(out, "RefMessage#1(0xe0) motan.TestSubMsg len=5") ||
+		!strings.Contains(out, "2 myInt: DirectInt32(0x56) 6") {
+		t.Errorf("wrong dump with schema. err:%v, dump:\n%s", err, out)
+	}
+}
+
+func TestDumpError(t *testing.T) {
+	buf := breeze.NewBuffer(64)
+	breeze.WriteValue(buf, []string{"abc"})
+	data := buf.Bytes()
+	tests := []struct {
+		data   []byte
+		errMsg string
+	}{
+		{data[:len(data)-1], "offset 3: breeze: not enough bytes"},
+		{[]byte{breeze.MapType, 10}, "offset 0: size 10 exceeds remaining 0 bytes"},
+		{[]byte{0xe1, 0, 0, 0, 0}, "offset 0: message type RefMessage#2 not found in context"},
+		{[]byte{breeze.MessageType, 1, 'A', 0, 0, 0, 3, 0x50}, "offset 0: message length 3 exceeds remaining 1 bytes"},
+		{[]byte{0xc0}, "offset 0: unknown type Unknown(0xc0)"},
+	}
+	for _, test := range tests {
+		_, err := dumpBytes(test.data, nil)
+		if err == nil || err.Error() != test.errMsg {
+			t.Errorf("wrong error. data:%x, expect:%s, real:%v", test.data, test.errMsg, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Command breeze-dump prints breeze binary data as an annotated tree.
+
+Usage:
+
+	breeze-dump [-schema file.breeze]... [file]
+
+The data is read from stdin if the file is omitted or is "-".
+Each line is a node with the byte offset, the type byte (such as DirectInt32(0x5c), PackedMap(0xdb) and RefMessage#3(0xe2)),
+the length and the value. The names of ref messages are resolved by the message types read before.
+The field names of messages are got from the schemas in data or the schema files loaded by -schema, for example:
+
+	 0  Message(0xde) motan.TestMsg len=354
+	20    1 myInt: DirectInt32(0x5c) 12
+	22    2 myString: DirectString(0x08) len=8 "jiernoce"
+	32    3 myMap: PackedMap(0xdb) size=1 String => Message motan.TestSubMsg
+	53      key: String len=2 "m1"
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/weibreeze/breeze-go"
+	"github.com/weibreeze/breeze-go/idl"
+)
+
+// schemaFiles is a repeatable flag of schema files
+type schemaFiles []string
+
+func (s *schemaFiles) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *schemaFiles) Set(path string) error {
+	*s = append(*s, path)
+	return nil
+}
+
+var schemaPaths schemaFiles
+
+func main() {
+	flag.Var(&schemaPaths, "schema", "breeze schema file to show field names, can be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: breeze-dump [-schema file.breeze]... [file]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), schemaPaths); err != nil {
+		fmt.Fprintln(os.Stderr, "breeze-dump:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, schemaPaths []string) error {
+	schemas, err := loadSchemas(schemaPaths)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if path == "" || path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	d := &dumper{w: os.Stdout, buf: breeze.CreateBuffer(data), schemas: schemas}
+	return d.dump()
+}
+
+// loadSchemas parse the schema files, the schemas can be found by name or alias
+func loadSchemas(paths []string) (map[string]*breeze.Schema, error) {
+	schemas := make(map[string]*breeze.Schema, 16)
+	for _, path := range paths {
+		f, err := idl.ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, schema := range f.Schemas() {
+			schemas[schema.Name] = schema
+			if schema.Alias != "" {
+				schemas[schema.Alias] = schema
+			}
+		}
+	}
+	return schemas, nil
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"strings"
+
+	"github.com/weibreeze/breeze-go/idl"
+)
+
+// breezePackage is the import path of breeze package used by generated codes
+const breezePackage = "github.com/weibreeze/breeze-go"
+
+// basic types of breeze schema -> go type and the name suffix of breeze functions, such as WriteInt32Field and ReadInt32
+var basicTypes = map[string]struct{ goType, funcName string }{
+	"bool":    {"bool", "Bool"},
+	"string":  {"string", "String"},
+	"byte":    {"byte", "Byte"},
+	"bytes":   {"[]byte", "Bytes"},
+	"int16":   {"int16", "Int16"},
+	"int32":   {"int32", "Int32"},
+	"int64":   {"int64", "Int64"},
+	"float32": {"float32", "Float32"},
+	"float64": {"float64", "Float64"},
+}
+
+// basic types which have array functions, such as WriteInt32ArrayElems and ReadInt32Array
+var arrayFuncTypes = map[string]bool{"string": true, "int32": true, "int64": true}
+
+// methods of generated types and the promoted field and methods of embedded UnknownFields, fields can not use these names
+var methodNames = map[string]bool{"WriteTo": true, "ReadFrom": true, "ReadEnum": true, "GetName": true, "GetAlias": true, "GetSchema": true,
+	"UnknownFields": true, "WriteUnknownFields": true, "ReadUnknownField": true, "GetUnknownField": true, "UnknownFieldIndexes": true,
+	"ClearUnknownFields": true}
+
+// definition is a message or enum which can be referenced by field types
+type definition struct {
+	goName    string // go type name
+	schemaVar string // name of schema variable
+	enum      bool
+}
+
+type generator struct {
+	pkg         string                 // go package name of generated codes
+	q           string                 // qualifier of breeze package, it is empty when generating into breeze package itself
+	definitions map[string]*definition // full name -> definition
+	buf         bytes.Buffer
+}
+
+// newGenerator create a generator of the schema files which are generated into same go package.
+// messages and enums can reference the definitions in any of the files.
+func newGenerator(pkg string, files []*idl.File) (*generator, error) {
+	g := &generator{pkg: pkg, q: "breeze.", definitions: make(map[string]*definition)}
+	if pkg == "breeze" {
+		g.q = ""
+	}
+	idents := make(map[string]string) // go identifier -> definition name
+	addIdent := func(f *idl.File, pos idl.Position, ident string, name string) error {
+		if former, ok := idents[ident]; ok {
+			return &idl.Error{File: f.Name, Pos: pos, Msg: fmt.Sprintf("go name %s of %s conflicts with %s", ident, name, former)}
+		}
+		idents[ident] = name
+		return nil
+	}
+	for _, f := range files {
+		for _, m := range f.Messages {
+			d := newDefinition(m.Name, false)
+			if err := addIdent(f, m.Pos, d.goName, f.FullName(m.Name)); err != nil {
+				return nil, err
+			}
+			if err := addIdent(f, m.Pos, d.schemaVar, f.FullName(m.Name)); err != nil {
+				return nil, err
+			}
+			g.definitions[f.FullName(m.Name)] = d
+		}
+		for _, e := range f.Enums {
+			d := newDefinition(e.Name, true)
+			if err := addIdent(f, e.Pos, d.goName, f.FullName(e.Name)); err != nil {
+				return nil, err
+			}
+			if err := addIdent(f, e.Pos, d.schemaVar, f.FullName(e.Name)); err != nil {
+				return nil, err
+			}
+			for _, v := range e.Values {
+				if err := addIdent(f, v.Pos, d.goName+exported(v.Name), f.FullName(e.Name)+"."+v.Name); err != nil {
+					return nil, err
+				}
+			}
+			g.definitions[f.FullName(e.Name)] = d
+		}
+	}
+	for _, f := range files {
+		for _, m := range f.Messages {
+			for _, field := range m.Fields {
+				if methodNames[exported(field.Name)] {
+					return nil, &idl.Error{File: f.Name, Pos: field.Pos, Msg: fmt.Sprintf("field name %s conflicts with method %s", field.Name, exported(field.Name))}
+				}
+				if err := g.checkType(f, field.Type); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+func newDefinition(name string, enum bool) *definition {
+	goName := exported(name)
+	return &definition{goName: goName, schemaVar: unexported(goName) + "BreezeSchema", enum: enum}
+}
+
+// checkType check whether the field type can be generated
+func (g *generator) checkType(f *idl.File, t *idl.Type) error {
+	switch {
+	case t.Name == idl.MapType:
+		if !t.Key.IsBasic() || t.Key.Name == "bytes" {
+			return &idl.Error{File: f.Name, Pos: t.Key.Pos, Msg: "map key must be a basic type except bytes, got " + t.Key.String()}
+		}
+		return g.checkType(f, t.Value)
+	case t.Name == idl.ArrayType:
+		return g.checkType(f, t.Value)
+	case t.IsBasic():
+		return nil
+	}
+	if g.lookup(f, t) == nil {
+		return &idl.Error{File: f.Name, Pos: t.Pos, Msg: "unknown type " + t.Name}
+	}
+	return nil
+}
+
+// lookup find the message or enum definition of type. the name can be with or without package.
+func (g *generator) lookup(f *idl.File, t *idl.Type) *definition {
+	if d, ok := g.definitions[f.FullName(t.Name)]; ok {
+		return d
+	}
+	return g.definitions[t.Name]
+}
+
+// generate generate the go source of a schema file. the source is formatted by gofmt.
+func (g *generator) generate(f *idl.File) ([]byte, error) {
+	g.buf.Reset()
+	g.p("// Code generated by breeze-gen. DO NOT EDIT.")
+	g.p("// source: %s", filepath.Base(f.Name))
+	g.p("")
+	g.p("package %s", g.pkg)
+	g.p("")
+	hasMap := hasMapField(f)
+	if len(f.Enums) > 0 || hasMap || g.q != "" {
+		g.p("import (")
+		if len(f.Enums) > 0 {
+			g.p(`"errors"`)
+		}
+		if hasMap {
+			g.p(`"sort"`)
+		}
+		if len(f.Enums) > 0 {
+			g.p(`"strconv"`)
+		}
+		if g.q != "" {
+			g.p("")
+			g.p("%q", breezePackage)
+		}
+		g.p(")")
+	}
+	for _, e := range f.Enums {
+		g.generateEnum(f, e)
+	}
+	for _, m := range f.Messages {
+		g.generateMessage(f, m)
+	}
+	g.generateSchemas(f)
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil { // should not happen
+		return nil, fmt.Errorf("format generated codes of %s fail: %v", f.Name, err)
+	}
+	return src, nil
+}
+
+func (g *generator) generateEnum(f *idl.File, e *idl.Enum) {
+	d := g.definitions[f.FullName(e.Name)]
+	r := receiver(d.goName)
+	g.p("")
+	g.p("const (")
+	for _, v := range e.Values {
+		g.p("%s%s %s = %d", d.goName, exported(v.Name), d.goName, v.Number)
+	}
+	g.p(")")
+	g.p("")
+	g.p("type %s int", d.goName)
+	g.p("")
+	g.p("func (%s %s) WriteTo(buf *%sBuffer) error {", r, d.goName, g.q)
+	g.p("return %sWriteMessageWithoutType(buf, func(buf *%sBuffer) {", g.q, g.q)
+	g.p("%sWriteInt32Field(buf, %d, int32(%s))", g.q, idl.EnumFieldIndex, r)
+	g.p("})")
+	g.p("}")
+	g.p("")
+	g.p("func (%s %s) ReadFrom(buf *%sBuffer) error {", r, d.goName, g.q)
+	g.p(`return errors.New("can not read enum by Message.ReadFrom, Enum.ReadEnum is expected. name:" + %s.GetName())`, r)
+	g.p("}")
+	g.p("")
+	g.p("func (%s %s) ReadEnum(buf *%sBuffer, asAddr bool) (%sEnum, error) {", r, d.goName, g.q, g.q)
+	g.p("var number int32")
+	g.p("err := %sReadMessageField(buf, func(buf *%sBuffer, index int) (err error) {", g.q, g.q)
+	g.p("switch index {")
+	g.p("case %d:", idl.EnumFieldIndex)
+	g.p("err = %sReadInt32(buf, &number)", g.q)
+	g.p("default: //skip unknown field")
+	g.p("err = %sSkipValue(buf)", g.q)
+	g.p("}")
+	g.p("return err")
+	g.p("})")
+	g.p("if err != nil {")
+	g.p("return nil, err")
+	g.p("}")
+	g.p("var result %s", d.goName)
+	g.p("switch number {")
+	for _, v := range e.Values {
+		g.p("case %d:", v.Number)
+		g.p("result = %s%s", d.goName, exported(v.Name))
+	}
+	g.p("default:")
+	g.p(`return nil, errors.New("unknown enum number " + strconv.Itoa(int(number)))`)
+	g.p("}")
+	g.p("if asAddr {")
+	g.p("return &result, nil")
+	g.p("}")
+	g.p("return result, nil")
+	g.p("}")
+	g.generateSchemaMethods(d)
+}
+
+func (g *generator) generateMessage(f *idl.File, m *idl.Message) {
+	d := g.definitions[f.FullName(m.Name)]
+	r := receiver(d.goName)
+	g.p("")
+	g.p("type %s struct {", d.goName)
+	for _, field := range m.Fields {
+		g.p("%s %s", exported(field.Name), g.goType(f, field.Type))
+	}
+	g.p("%sUnknownFields", g.q)
+	g.p("}")
+
+	g.p("")
+	g.p("func (%s *%s) WriteTo(buf *%sBuffer) error {", r, d.goName, g.q)
+	g.p("return %sWriteMessageWithoutType(buf, func(buf *%sBuffer) {", g.q, g.q)
+	for _, field := range m.Fields {
+		g.writeField(f, field, r+"."+exported(field.Name))
+	}
+	g.p("%s.WriteUnknownFields(buf)", r)
+	g.p("})")
+	g.p("}")
+
+	g.p("")
+	g.p("func (%s *%s) ReadFrom(buf *%sBuffer) error {", r, d.goName, g.q)
+	g.p("%s.ClearUnknownFields()", r)
+	g.p("return %sReadMessageField(buf, func(buf *%sBuffer, index int) (err error) {", g.q, g.q)
+	g.p("switch index {")
+	for _, field := range m.Fields {
+		g.p("case %d:", field.Index)
+		g.readField(f, field, r+"."+exported(field.Name))
+	}
+	g.p("default: //keep unknown field")
+	g.p("err = %s.ReadUnknownField(buf, index)", r)
+	g.p("}")
+	g.p("return err")
+	g.p("})")
+	g.p("}")
+	g.generateSchemaMethods(d)
+}
+
+func (g *generator) generateSchemaMethods(d *definition) {
+	r := receiver(d.goName)
+	ptr := "*"
+	if d.enum {
+		ptr = ""
+	}
+	for _, method := range []string{"Name", "Alias"} {
+		g.p("")
+		g.p("func (%s %s%s) Get%s() string {", r, ptr, d.goName, method)
+		g.p("return %s.%s", d.schemaVar, method)
+		g.p("}")
+	}
+	g.p("")
+	g.p("func (%s %s%s) GetSchema() *%sSchema {", r, ptr, d.goName, g.q)
+	g.p("return %s", d.schemaVar)
+	g.p("}")
+}
+
+// generateSchemas generate schema variables, and init them and register messages and enums in init()
+func (g *generator) generateSchemas(f *idl.File) {
+	if len(f.Messages)+len(f.Enums) == 0 {
+		return
+	}
+	g.p("")
+	for _, e := range f.Enums {
+		g.p("var %s *%sSchema", g.definitions[f.FullName(e.Name)].schemaVar, g.q)
+	}
+	for _, m := range f.Messages {
+		g.p("var %s *%sSchema", g.definitions[f.FullName(m.Name)].schemaVar, g.q)
+	}
+	g.p("")
+	g.p("func init() {")
+	for i, e := range f.Enums {
+		if i > 0 {
+			g.p("")
+		}
+		d := g.definitions[f.FullName(e.Name)]
+		g.newSchema(d, f.FullName(e.Name), e.Alias)
+		g.p("%s.PutFields(&%sField{Index: %d, Name: %q, Type: \"int32\"})", d.schemaVar, g.q, idl.EnumFieldIndex, idl.EnumFieldName)
+		for _, v := range e.Values {
+			g.p("%s.PutEnumValue(%d, %q)", d.schemaVar, v.Number, v.Name)
+		}
+		g.p("%sRegisterEnum(%s(0))", g.q, d.goName)
+	}
+	for i, m := range f.Messages {
+		if i > 0 || len(f.Enums) > 0 {
+			g.p("")
+		}
+		d := g.definitions[f.FullName(m.Name)]
+		g.newSchema(d, f.FullName(m.Name), m.Alias)
+		for _, field := range m.Fields {
+			g.p("%s.PutFields(&%sField{Index: %d, Name: %q, Type: %q})", d.schemaVar, g.q, field.Index, field.Name, field.Type.String())
+		}
+		g.p("%sRegisterMessage(&%s{})", g.q, d.goName)
+	}
+	g.p("}")
+}
+
+func (g *generator) newSchema(d *definition, name string, alias string) {
+	if alias == "" {
+		g.p("%s = &%sSchema{Name: %q}", d.schemaVar, g.q, name)
+	} else {
+		g.p("%s = &%sSchema{Name: %q, Alias: %q}", d.schemaVar, g.q, name, alias)
+	}
+}
+
+//========== write fields =====================
+
+func (g *generator) writeField(f *idl.File, field *idl.Field, v string) {
+	t := field.Type
+	switch t.Name {
+	case idl.MapType, idl.ArrayType:
+		funcName := "WriteMapField"
+		if t.Name == idl.ArrayType {
+			funcName = "WriteArrayField"
+		}
+		g.p("if len(%s) > 0 {", v)
+		g.p("%s%s(buf, %d, len(%s), func(buf *%sBuffer) {", g.q, funcName, field.Index, v, g.q)
+		g.writeElems(f, t, v, 1)
+		g.p("})")
+		g.p("}")
+	default:
+		if t.IsBasic() {
+			g.p("%sWrite%sField(buf, %d, %s)", g.q, basicTypes[t.Name].funcName, field.Index, v)
+			return
+		}
+		g.p("if %s != nil {", v)
+		g.p("%sWriteMessageField(buf, %d, %s)", g.q, field.Index, v)
+		g.p("}")
+	}
+}
+
+// writeElems write the element types and the elements of map or array v. the element types of nested map or array
+// are only written when it is not empty, because they are not read for empty one.
+func (g *generator) writeElems(f *idl.File, t *idl.Type, v string, depth int) {
+	v1 := fmt.Sprintf("v%d", depth)
+	if depth > 1 {
+		g.p("if len(%s) > 0 {", v)
+	}
+	if t.Name == idl.ArrayType && arrayFuncTypes[t.Value.Name] {
+		g.p("%sWrite%sArrayElems(buf, %s)", g.q, basicTypes[t.Value.Name].funcName, v)
+	} else if t.Name == idl.ArrayType {
+		g.writeType(f, t.Value)
+		if depth > 1 {
+			g.p("}")
+		}
+		g.p("for _, %s := range %s {", v1, v)
+		g.writeValue(f, t.Value, v1, depth)
+		g.p("}")
+		return
+	} else {
+		g.writeType(f, t.Key)
+		g.writeType(f, t.Value)
+		if depth > 1 {
+			g.p("}")
+		}
+		g.writeMapEntries(f, t, v, depth)
+		return
+	}
+	if depth > 1 {
+		g.p("}")
+	}
+}
+
+// writeMapEntries write the entries of map v. the entries are written in ascending key order if the buffer is canonical
+func (g *generator) writeMapEntries(f *idl.File, t *idl.Type, v string, depth int) {
+	k1, v1, w1, ks1 := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth), fmt.Sprintf("w%d", depth), fmt.Sprintf("ks%d", depth)
+	g.p("%s := func(%s %s, %s %s) {", w1, k1, g.goType(f, t.Key), v1, g.goType(f, t.Value))
+	g.writeValue(f, t.Key, k1, depth)
+	g.writeValue(f, t.Value, v1, depth)
+	g.p("}")
+	g.p("if buf.IsCanonical() {")
+	g.p("%s := make([]%s, 0, len(%s))", ks1, g.goType(f, t.Key), v)
+	g.p("for %s := range %s {", k1, v)
+	g.p("%s = append(%s, %s)", ks1, ks1, k1)
+	g.p("}")
+	if t.Key.Name == "bool" {
+		g.p("sort.Slice(%s, func(i, j int) bool { return !%s[i] && %s[j] })", ks1, ks1, ks1)
+	} else {
+		g.p("sort.Slice(%s, func(i, j int) bool { return %s[i] < %s[j] })", ks1, ks1, ks1)
+	}
+	g.p("for _, %s := range %s {", k1, ks1)
+	g.p("%s(%s, %s[%s])", w1, k1, v, k1)
+	g.p("}")
+	g.p("} else {")
+	g.p("for %s, %s := range %s {", k1, v1, v)
+	g.p("%s(%s, %s)", w1, k1, v1)
+	g.p("}")
+	g.p("}")
+}
+
+func (g *generator) writeType(f *idl.File, t *idl.Type) {
+	switch {
+	case t.Name == idl.MapType:
+		g.p("%sWritePackedMapType(buf)", g.q)
+	case t.Name == idl.ArrayType:
+		g.p("%sWritePackedArrayType(buf)", g.q)
+	case t.IsBasic():
+		g.p("%sWrite%sType(buf)", g.q, basicTypes[t.Name].funcName)
+	default:
+		g.p("%sWriteMessageType(buf, %s.Name)", g.q, g.lookup(f, t).schemaVar)
+	}
+}
+
+func (g *generator) writeValue(f *idl.File, t *idl.Type, v string, depth int) {
+	switch {
+	case t.Name == idl.MapType:
+		g.p("%sWritePackedMap(buf, false, len(%s), func(buf *%sBuffer) {", g.q, v, g.q)
+		g.writeElems(f, t, v, depth+1)
+		g.p("})")
+	case t.Name == idl.ArrayType:
+		g.p("%sWritePackedArray(buf, false, len(%s), func(buf *%sBuffer) {", g.q, v, g.q)
+		g.writeElems(f, t, v, depth+1)
+		g.p("})")
+	case t.IsBasic():
+		g.p("%sWrite%s(buf, %s, false)", g.q, basicTypes[t.Name].funcName, v)
+	default:
+		g.p("%s.WriteTo(buf)", v)
+	}
+}
+
+//========== read fields =====================
+
+func (g *generator) readField(f *idl.File, field *idl.Field, v string) {
+	t := field.Type
+	switch {
+	case t.Name == idl.ArrayType && arrayFuncTypes[t.Value.Name]:
+		g.p("%s, err = %sRead%sArray(buf, true)", v, g.q, basicTypes[t.Value.Name].funcName)
+	case t.IsContainer():
+		if t.Name == idl.MapType {
+			g.p("size, err := %sReadPackedMapSize(buf, true)", g.q)
+		} else {
+			g.p("size, err := %sReadPackedArraySize(buf, true)", g.q)
+		}
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.readElems(f, t, v, "size", 1)
+	case t.IsBasic():
+		g.p("err = %sRead%s(buf, &%s)", g.q, basicTypes[t.Name].funcName, v)
+	default:
+		d := g.lookup(f, t)
+		if d.enum {
+			g.p("var result interface{}")
+			g.p("if result, err = %sReadByEnum(buf, %s(0), true); err == nil {", g.q, d.goName)
+			g.p("%s = result.(*%s)", v, d.goName)
+			g.p("}")
+			return
+		}
+		g.p("%s = &%s{}", v, d.goName)
+		g.p("err = %sReadByMessage(buf, %s)", g.q, v)
+	}
+}
+
+// readElems make map or array v with size, and read the elements by ReadPacked
+func (g *generator) readElems(f *idl.File, t *idl.Type, v string, size string, depth int) {
+	k1, v1 := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+	assign := "="
+	if depth > 1 {
+		assign = ":="
+	}
+	if t.Name == idl.MapType {
+		g.p("%s %s make(%s, %s)", v, assign, g.goType(f, t), size)
+	} else {
+		g.p("%s %s make(%s, 0, %s)", v, assign, g.goType(f, t), size)
+	}
+	if depth == 1 {
+		g.p("return %sReadPacked(buf, %s, %t, func(buf *%sBuffer) error {", g.q, size, t.Name == idl.MapType, g.q)
+	} else {
+		g.p("err = %sReadPacked(buf, %s, %t, func(buf *%sBuffer) error {", g.q, size, t.Name == idl.MapType, g.q)
+	}
+	if t.Name == idl.MapType {
+		g.readValue(f, t.Key, k1, depth)
+		g.readValue(f, t.Value, v1, depth)
+		g.p("%s[%s] = %s", v, k1, v1)
+	} else {
+		g.readValue(f, t.Value, v1, depth)
+		g.p("%s = append(%s, %s)", v, v, v1)
+	}
+	g.p("return nil")
+	g.p("})")
+	if depth > 1 {
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+	}
+}
+
+// readValue declare v and read it without type. it returns the error directly.
+func (g *generator) readValue(f *idl.File, t *idl.Type, v string, depth int) {
+	switch {
+	case t.Name == idl.ArrayType && arrayFuncTypes[t.Value.Name]:
+		g.p("%s, err := %sRead%sArray(buf, false)", v, g.q, basicTypes[t.Value.Name].funcName)
+	case t.IsContainer():
+		size := fmt.Sprintf("size%d", depth+1)
+		g.p("%s, err := %sReadPackedSize(buf, false)", size, g.q)
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		g.readElems(f, t, v, size, depth+1)
+		return
+	case t.Name == "byte":
+		g.p("%s, err := buf.ReadByte()", v)
+	case t.IsBasic():
+		g.p("%s, err := %sRead%sWithoutType(buf)", v, g.q, basicTypes[t.Name].funcName)
+	default:
+		d := g.lookup(f, t)
+		if d.enum {
+			g.p("enum%d, err := %s(0).ReadEnum(buf, true)", depth, d.goName)
+			g.p("if err != nil {")
+			g.p("return err")
+			g.p("}")
+			g.p("%s := enum%d.(*%s)", v, depth, d.goName)
+			return
+		}
+		g.p("%s := &%s{}", v, d.goName)
+		g.p("if err := %s.ReadFrom(buf); err != nil {", v)
+		g.p("return err")
+		g.p("}")
+		return
+	}
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+}
+
+// goType get the go type of field type. messages and enums are declared as pointer.
+func (g *generator) goType(f *idl.File, t *idl.Type) string {
+	switch {
+	case t.Name == idl.MapType:
+		return "map[" + g.goType(f, t.Key) + "]" + g.goType(f, t.Value)
+	case t.Name == idl.ArrayType:
+		return "[]" + g.goType(f, t.Value)
+	case t.IsBasic():
+		return basicTypes[t.Name].goType
+	}
+	return "*" + g.lookup(f, t).goName
+}
+
+// hasMapField check whether any message in file has map field, include the map in array or map
+func hasMapField(f *idl.File) bool {
+	for _, m := range f.Messages {
+		for _, field := range m.Fields {
+			for t := field.Type; t != nil; t = t.Value {
+				if t.Name == idl.MapType {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+// exported get the exported go name, such as myInt -> MyInt
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func unexported(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// receiver get the receiver name of type, such as TestMsg -> t
+func receiver(goName string) string {
+	return strings.ToLower(goName[:1])
+}
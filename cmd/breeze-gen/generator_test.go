@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/weibreeze/breeze-go/idl"
+)
+
+var testSchemaFiles = []string{"../../idl/testdata/testmsg.breeze", "testdata/nested.breeze"}
+
+func parseFiles(t *testing.T, paths ...string) []*idl.File {
+	files := make([]*idl.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := idl.ParseFile(path)
+		if err != nil {
+			t.Fatalf("parse fail. err:%v", err)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// the generated codes in internal/testmsg should be regenerated by go generate when the generator changed
+func TestGenerate(t *testing.T) {
+	files := parseFiles(t, testSchemaFiles...)
+	g, err := newGenerator("testmsg", files)
+	if err != nil {
+		t.Fatalf("create generator fail. err:%v", err)
+	}
+	for _, f := range files {
+		src, err := g.generate(f)
+		if err != nil {
+			t.Fatalf("generate fail. err:%v", err)
+		}
+		expect, err := ioutil.ReadFile("internal/testmsg/" + strings.TrimSuffix(f.Name[strings.LastIndex(f.Name, "/")+1:], ".breeze") + generatedFileSuffix)
+		if err != nil {
+			t.Fatalf("read generated file fail. err:%v", err)
+		}
+		if !bytes.Equal(src, expect) {
+			t.Errorf("generated codes of %s are changed, run go generate in internal/testmsg", f.Name)
+		}
+	}
+}
+
+func TestGenerateBreezePackage(t *testing.T) {
+	files := parseFiles(t, testSchemaFiles[0])
+	g, err := newGenerator("breeze", files)
+	if err != nil {
+		t.Fatalf("create generator fail. err:%v", err)
+	}
+	src, err := g.generate(files[0])
+	if err != nil {
+		t.Fatalf("generate fail. err:%v", err)
+	}
+	af, err := parser.ParseFile(token.NewFileSet(), "testmsg.breeze.go", src, parser.ImportsOnly)
+	if err != nil {
+		t.Fatalf("parse generated codes fail. err:%v", err)
+	}
+	if af.Name.Name != "breeze" || len(af.Imports) != 3 || bytes.Contains(src, []byte("breeze.")) {
+		t.Errorf("breeze package should not be imported or qualified in breeze package. source:\n%s", src)
+	}
+}
+
+func TestGenerateBoolKey(t *testing.T) {
+	f, err := idl.Parse("a.breeze", []byte(`message A { map<bool, int32> m = 1; }`))
+	if err != nil {
+		t.Fatalf("parse fail. err:%v", err)
+	}
+	g, err := newGenerator("a", []*idl.File{f})
+	if err != nil {
+		t.Fatalf("create generator fail. err:%v", err)
+	}
+	src, err := g.generate(f)
+	if err != nil || !bytes.Contains(src, []byte("return !ks1[i] && ks1[j]")) {
+		t.Errorf("bool keys should be sorted with false first. err:%v, source:\n%s", err, src)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	tests := []struct {
+		src    string
+		errMsg string
+	}{
+		{`message A { B b = 1; }`, "a.breeze:1:13: unknown type B"},
+		{`message A { map<bytes, int32> m = 1; }`, "a.breeze:1:17: map key must be a basic type except bytes, got bytes"},
+		{`message A { map<A, int32> m = 1; }`, "a.breeze:1:17: map key must be a basic type except bytes, got A"},
+		{`message A { int32 getName = 1; }`, "a.breeze:1:13: field name getName conflicts with method GetName"},
+		{`message A { int32 unknownFields = 1; }`, "a.breeze:1:13: field name unknownFields conflicts with method UnknownFields"},
+		{`message A { string readUnknownField = 1; }`, "a.breeze:1:13: field name readUnknownField conflicts with method ReadUnknownField"},
+		{`message a {} message A {}`, "a.breeze:1:14: go name A of A conflicts with a"},
+		{`enum E { A = 1; } message EA {}`, "a.breeze:1:10: go name EA of E.A conflicts with EA"},
+		{`package p; message A { x.A a = 1; }`, "a.breeze:1:24: unknown type x.A"},
+	}
+	for _, test := range tests {
+		f, err := idl.Parse("a.breeze", []byte(test.src))
+		if err != nil {
+			t.Fatalf("parse fail. src:%s, err:%v", test.src, err)
+		}
+		_, err = newGenerator("a", []*idl.File{f})
+		if err == nil || err.Error() != test.errMsg {
+			t.Errorf("wrong error. src:%s, expect:%s, real:%v", test.src, test.errMsg, err)
+		}
+	}
+}
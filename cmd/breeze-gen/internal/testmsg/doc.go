@@ -0,0 +1,5 @@
+// Package testmsg is generated by breeze-gen from the schema of the messages in breeze package test.go.
+// it keeps the generated codes compiling and compatible with the messages generated by breeze-generator.
+package testmsg
+
+//go:generate go run github.com/weibreeze/breeze-go/cmd/breeze-gen ../../../../idl/testdata/testmsg.breeze ../../testdata/nested.breeze
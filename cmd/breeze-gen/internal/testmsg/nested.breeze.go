@@ -0,0 +1,416 @@
+// Code generated by breeze-gen. DO NOT EDIT.
+// source: nested.breeze
+
+package testmsg
+
+import (
+	"sort"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+type NestedMsg struct {
+	NestedMap    map[string]map[int64][]*TestSubMsg
+	StringArrays [][]string
+	Bools        []bool
+	EnumMap      map[byte]*MyEnum
+	FloatMaps    []map[string]float64
+	TestMsg      *TestMsg
+	BytesArray   [][]byte
+	MyInt16      int16
+	Int64Arrays  map[int16][][]int64
+	breeze.UnknownFields
+}
+
+func (n *NestedMsg) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		if len(n.NestedMap) > 0 {
+			breeze.WriteMapField(buf, 1, len(n.NestedMap), func(buf *breeze.Buffer) {
+				breeze.WriteStringType(buf)
+				breeze.WritePackedMapType(buf)
+				w1 := func(k1 string, v1 map[int64][]*TestSubMsg) {
+					breeze.WriteString(buf, k1, false)
+					breeze.WritePackedMap(buf, false, len(v1), func(buf *breeze.Buffer) {
+						if len(v1) > 0 {
+							breeze.WriteInt64Type(buf)
+							breeze.WritePackedArrayType(buf)
+						}
+						w2 := func(k2 int64, v2 []*TestSubMsg) {
+							breeze.WriteInt64(buf, k2, false)
+							breeze.WritePackedArray(buf, false, len(v2), func(buf *breeze.Buffer) {
+								if len(v2) > 0 {
+									breeze.WriteMessageType(buf, testSubMsgBreezeSchema.Name)
+								}
+								for _, v3 := range v2 {
+									v3.WriteTo(buf)
+								}
+							})
+						}
+						if buf.IsCanonical() {
+							ks2 := make([]int64, 0, len(v1))
+							for k2 := range v1 {
+								ks2 = append(ks2, k2)
+							}
+							sort.Slice(ks2, func(i, j int) bool { return ks2[i] < ks2[j] })
+							for _, k2 := range ks2 {
+								w2(k2, v1[k2])
+							}
+						} else {
+							for k2, v2 := range v1 {
+								w2(k2, v2)
+							}
+						}
+					})
+				}
+				if buf.IsCanonical() {
+					ks1 := make([]string, 0, len(n.NestedMap))
+					for k1 := range n.NestedMap {
+						ks1 = append(ks1, k1)
+					}
+					sort.Slice(ks1, func(i, j int) bool { return ks1[i] < ks1[j] })
+					for _, k1 := range ks1 {
+						w1(k1, n.NestedMap[k1])
+					}
+				} else {
+					for k1, v1 := range n.NestedMap {
+						w1(k1, v1)
+					}
+				}
+			})
+		}
+		if len(n.StringArrays) > 0 {
+			breeze.WriteArrayField(buf, 2, len(n.StringArrays), func(buf *breeze.Buffer) {
+				breeze.WritePackedArrayType(buf)
+				for _, v1 := range n.StringArrays {
+					breeze.WritePackedArray(buf, false, len(v1), func(buf *breeze.Buffer) {
+						if len(v1) > 0 {
+							breeze.WriteStringArrayElems(buf, v1)
+						}
+					})
+				}
+			})
+		}
+		if len(n.Bools) > 0 {
+			breeze.WriteArrayField(buf, 3, len(n.Bools), func(buf *breeze.Buffer) {
+				breeze.WriteBoolType(buf)
+				for _, v1 := range n.Bools {
+					breeze.WriteBool(buf, v1, false)
+				}
+			})
+		}
+		if len(n.EnumMap) > 0 {
+			breeze.WriteMapField(buf, 4, len(n.EnumMap), func(buf *breeze.Buffer) {
+				breeze.WriteByteType(buf)
+				breeze.WriteMessageType(buf, myEnumBreezeSchema.Name)
+				w1 := func(k1 byte, v1 *MyEnum) {
+					breeze.WriteByte(buf, k1, false)
+					v1.WriteTo(buf)
+				}
+				if buf.IsCanonical() {
+					ks1 := make([]byte, 0, len(n.EnumMap))
+					for k1 := range n.EnumMap {
+						ks1 = append(ks1, k1)
+					}
+					sort.Slice(ks1, func(i, j int) bool { return ks1[i] < ks1[j] })
+					for _, k1 := range ks1 {
+						w1(k1, n.EnumMap[k1])
+					}
+				} else {
+					for k1, v1 := range n.EnumMap {
+						w1(k1, v1)
+					}
+				}
+			})
+		}
+		if len(n.FloatMaps) > 0 {
+			breeze.WriteArrayField(buf, 5, len(n.FloatMaps), func(buf *breeze.Buffer) {
+				breeze.WritePackedMapType(buf)
+				for _, v1 := range n.FloatMaps {
+					breeze.WritePackedMap(buf, false, len(v1), func(buf *breeze.Buffer) {
+						if len(v1) > 0 {
+							breeze.WriteStringType(buf)
+							breeze.WriteFloat64Type(buf)
+						}
+						w2 := func(k2 string, v2 float64) {
+							breeze.WriteString(buf, k2, false)
+							breeze.WriteFloat64(buf, v2, false)
+						}
+						if buf.IsCanonical() {
+							ks2 := make([]string, 0, len(v1))
+							for k2 := range v1 {
+								ks2 = append(ks2, k2)
+							}
+							sort.Slice(ks2, func(i, j int) bool { return ks2[i] < ks2[j] })
+							for _, k2 := range ks2 {
+								w2(k2, v1[k2])
+							}
+						} else {
+							for k2, v2 := range v1 {
+								w2(k2, v2)
+							}
+						}
+					})
+				}
+			})
+		}
+		if n.TestMsg != nil {
+			breeze.WriteMessageField(buf, 6, n.TestMsg)
+		}
+		if len(n.BytesArray) > 0 {
+			breeze.WriteArrayField(buf, 7, len(n.BytesArray), func(buf *breeze.Buffer) {
+				breeze.WriteBytesType(buf)
+				for _, v1 := range n.BytesArray {
+					breeze.WriteBytes(buf, v1, false)
+				}
+			})
+		}
+		breeze.WriteInt16Field(buf, 8, n.MyInt16)
+		if len(n.Int64Arrays) > 0 {
+			breeze.WriteMapField(buf, 9, len(n.Int64Arrays), func(buf *breeze.Buffer) {
+				breeze.WriteInt16Type(buf)
+				breeze.WritePackedArrayType(buf)
+				w1 := func(k1 int16, v1 [][]int64) {
+					breeze.WriteInt16(buf, k1, false)
+					breeze.WritePackedArray(buf, false, len(v1), func(buf *breeze.Buffer) {
+						if len(v1) > 0 {
+							breeze.WritePackedArrayType(buf)
+						}
+						for _, v2 := range v1 {
+							breeze.WritePackedArray(buf, false, len(v2), func(buf *breeze.Buffer) {
+								if len(v2) > 0 {
+									breeze.WriteInt64ArrayElems(buf, v2)
+								}
+							})
+						}
+					})
+				}
+				if buf.IsCanonical() {
+					ks1 := make([]int16, 0, len(n.Int64Arrays))
+					for k1 := range n.Int64Arrays {
+						ks1 = append(ks1, k1)
+					}
+					sort.Slice(ks1, func(i, j int) bool { return ks1[i] < ks1[j] })
+					for _, k1 := range ks1 {
+						w1(k1, n.Int64Arrays[k1])
+					}
+				} else {
+					for k1, v1 := range n.Int64Arrays {
+						w1(k1, v1)
+					}
+				}
+			})
+		}
+		n.WriteUnknownFields(buf)
+	})
+}
+
+func (n *NestedMsg) ReadFrom(buf *breeze.Buffer) error {
+	n.ClearUnknownFields()
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			size, err := breeze.ReadPackedMapSize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.NestedMap = make(map[string]map[int64][]*TestSubMsg, size)
+			return breeze.ReadPacked(buf, size, true, func(buf *breeze.Buffer) error {
+				k1, err := breeze.ReadStringWithoutType(buf)
+				if err != nil {
+					return err
+				}
+				size2, err := breeze.ReadPackedSize(buf, false)
+				if err != nil {
+					return err
+				}
+				v1 := make(map[int64][]*TestSubMsg, size2)
+				err = breeze.ReadPacked(buf, size2, true, func(buf *breeze.Buffer) error {
+					k2, err := breeze.ReadInt64WithoutType(buf)
+					if err != nil {
+						return err
+					}
+					size3, err := breeze.ReadPackedSize(buf, false)
+					if err != nil {
+						return err
+					}
+					v2 := make([]*TestSubMsg, 0, size3)
+					err = breeze.ReadPacked(buf, size3, false, func(buf *breeze.Buffer) error {
+						v3 := &TestSubMsg{}
+						if err := v3.ReadFrom(buf); err != nil {
+							return err
+						}
+						v2 = append(v2, v3)
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+					v1[k2] = v2
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				n.NestedMap[k1] = v1
+				return nil
+			})
+		case 2:
+			size, err := breeze.ReadPackedArraySize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.StringArrays = make([][]string, 0, size)
+			return breeze.ReadPacked(buf, size, false, func(buf *breeze.Buffer) error {
+				v1, err := breeze.ReadStringArray(buf, false)
+				if err != nil {
+					return err
+				}
+				n.StringArrays = append(n.StringArrays, v1)
+				return nil
+			})
+		case 3:
+			size, err := breeze.ReadPackedArraySize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.Bools = make([]bool, 0, size)
+			return breeze.ReadPacked(buf, size, false, func(buf *breeze.Buffer) error {
+				v1, err := breeze.ReadBoolWithoutType(buf)
+				if err != nil {
+					return err
+				}
+				n.Bools = append(n.Bools, v1)
+				return nil
+			})
+		case 4:
+			size, err := breeze.ReadPackedMapSize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.EnumMap = make(map[byte]*MyEnum, size)
+			return breeze.ReadPacked(buf, size, true, func(buf *breeze.Buffer) error {
+				k1, err := buf.ReadByte()
+				if err != nil {
+					return err
+				}
+				enum1, err := MyEnum(0).ReadEnum(buf, true)
+				if err != nil {
+					return err
+				}
+				v1 := enum1.(*MyEnum)
+				n.EnumMap[k1] = v1
+				return nil
+			})
+		case 5:
+			size, err := breeze.ReadPackedArraySize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.FloatMaps = make([]map[string]float64, 0, size)
+			return breeze.ReadPacked(buf, size, false, func(buf *breeze.Buffer) error {
+				size2, err := breeze.ReadPackedSize(buf, false)
+				if err != nil {
+					return err
+				}
+				v1 := make(map[string]float64, size2)
+				err = breeze.ReadPacked(buf, size2, true, func(buf *breeze.Buffer) error {
+					k2, err := breeze.ReadStringWithoutType(buf)
+					if err != nil {
+						return err
+					}
+					v2, err := breeze.ReadFloat64WithoutType(buf)
+					if err != nil {
+						return err
+					}
+					v1[k2] = v2
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				n.FloatMaps = append(n.FloatMaps, v1)
+				return nil
+			})
+		case 6:
+			n.TestMsg = &TestMsg{}
+			err = breeze.ReadByMessage(buf, n.TestMsg)
+		case 7:
+			size, err := breeze.ReadPackedArraySize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.BytesArray = make([][]byte, 0, size)
+			return breeze.ReadPacked(buf, size, false, func(buf *breeze.Buffer) error {
+				v1, err := breeze.ReadBytesWithoutType(buf)
+				if err != nil {
+					return err
+				}
+				n.BytesArray = append(n.BytesArray, v1)
+				return nil
+			})
+		case 8:
+			err = breeze.ReadInt16(buf, &n.MyInt16)
+		case 9:
+			size, err := breeze.ReadPackedMapSize(buf, true)
+			if err != nil {
+				return err
+			}
+			n.Int64Arrays = make(map[int16][][]int64, size)
+			return breeze.ReadPacked(buf, size, true, func(buf *breeze.Buffer) error {
+				k1, err := breeze.ReadInt16WithoutType(buf)
+				if err != nil {
+					return err
+				}
+				size2, err := breeze.ReadPackedSize(buf, false)
+				if err != nil {
+					return err
+				}
+				v1 := make([][]int64, 0, size2)
+				err = breeze.ReadPacked(buf, size2, false, func(buf *breeze.Buffer) error {
+					v2, err := breeze.ReadInt64Array(buf, false)
+					if err != nil {
+						return err
+					}
+					v1 = append(v1, v2)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				n.Int64Arrays[k1] = v1
+				return nil
+			})
+		default: //keep unknown field
+			err = n.ReadUnknownField(buf, index)
+		}
+		return err
+	})
+}
+
+func (n *NestedMsg) GetName() string {
+	return nestedMsgBreezeSchema.Name
+}
+
+func (n *NestedMsg) GetAlias() string {
+	return nestedMsgBreezeSchema.Alias
+}
+
+func (n *NestedMsg) GetSchema() *breeze.Schema {
+	return nestedMsgBreezeSchema
+}
+
+var nestedMsgBreezeSchema *breeze.Schema
+
+func init() {
+	nestedMsgBreezeSchema = &breeze.Schema{Name: "motan.NestedMsg", Alias: "Nested"}
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "nestedMap", Type: "map<string, map<int64, array<TestSubMsg>>>"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 2, Name: "stringArrays", Type: "array<array<string>>"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 3, Name: "bools", Type: "array<bool>"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 4, Name: "enumMap", Type: "map<byte, MyEnum>"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 5, Name: "floatMaps", Type: "array<map<string, float64>>"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 6, Name: "testMsg", Type: "motan.TestMsg"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 7, Name: "bytesArray", Type: "array<bytes>"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 8, Name: "myInt16", Type: "int16"})
+	nestedMsgBreezeSchema.PutFields(&breeze.Field{Index: 9, Name: "int64Arrays", Type: "map<int16, array<array<int64>>>"})
+	breeze.RegisterMessage(&NestedMsg{})
+}
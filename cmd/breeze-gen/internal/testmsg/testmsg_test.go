@@ -0,0 +1,125 @@
+package testmsg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+// the generated messages are compatible with the messages in breeze package, which have same schema
+func TestCompatible(t *testing.T) {
+	expect := breeze.GetBenchData(3)
+	buf := breeze.NewBuffer(256)
+	if err := breeze.WriteValue(buf, expect); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	msg := &TestMsg{}
+	if _, err := breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), msg); err != nil {
+		t.Fatalf("read generated message fail. err:%v", err)
+	}
+	if len(msg.MyMap) != len(expect.MyMap) || *msg.MyEnum != MyEnumE3 || len(msg.EnumArray) != 2 || msg.MyMap["k1"].MyInt != 1 {
+		t.Errorf("wrong generated message: %+v", msg)
+	}
+
+	buf = breeze.NewBuffer(256)
+	if err := breeze.WriteValue(buf, msg); err != nil {
+		t.Fatalf("write generated message fail. err:%v", err)
+	}
+	result := &breeze.TestMsg{}
+	if _, err := breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), result); err != nil {
+		t.Fatalf("read fail. err:%v", err)
+	}
+	if !reflect.DeepEqual(expect, result) {
+		t.Errorf("message not equal. expect:%+v, real:%+v", expect, result)
+	}
+}
+
+func TestNestedMsg(t *testing.T) {
+	e1, e2 := MyEnumE1, MyEnumE2
+	sub := &TestSubMsg{MyString: "sub", MyInt: 3, MyMap2: map[int32][]int32{1: {}, 2: {5, 6}}}
+	msg := &NestedMsg{
+		NestedMap:    map[string]map[int64][]*TestSubMsg{"a": {1: {sub, sub}, 2: {}}, "b": {}},
+		StringArrays: [][]string{{"x", "y"}, {}},
+		Bools:        []bool{true, false},
+		EnumMap:      map[byte]*MyEnum{1: &e1, 2: &e2},
+		FloatMaps:    []map[string]float64{{"f": 1.5}, {}},
+		TestMsg:      &TestMsg{MyInt: 7, SubMsg: sub, MyEnum: &e2},
+		BytesArray:   [][]byte{[]byte("b1"), {}},
+		MyInt16:      -16,
+		Int64Arrays:  map[int16][][]int64{3: {{1, 2}, {}}, 4: {}},
+	}
+	buf := breeze.NewBuffer(256)
+	if err := breeze.WriteValue(buf, msg); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	// the generated messages are registered
+	result, err := breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("read fail. err:%v", err)
+	}
+	if !reflect.DeepEqual(msg, result) {
+		t.Errorf("message not equal. expect:%+v, real:%+v", msg, result)
+	}
+	if msg.GetName() != "motan.NestedMsg" || msg.GetAlias() != "Nested" || msg.GetSchema().GetFieldByName("int64Arrays").Type != "map<int16, array<array<int64>>>" {
+		t.Errorf("wrong schema: %+v", msg.GetSchema())
+	}
+
+	// unknown fields are skipped
+	buf = breeze.NewBuffer(256)
+	breeze.WriteMessageType(buf, msg.GetName())
+	breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 100, "unknown")
+		breeze.WriteInt16Field(buf, 8, 16)
+	})
+	nested := &NestedMsg{}
+	if _, err = breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), nested); err != nil || nested.MyInt16 != 16 {
+		t.Errorf("skip unknown field fail. err:%v, message:%+v", err, nested)
+	}
+}
+
+// the schemas of generated messages and enums are registered, so they can be converted to json without schemas
+func TestJSON(t *testing.T) {
+	e1 := MyEnumE1
+	msg := &NestedMsg{EnumMap: map[byte]*MyEnum{1: &e1}, MyInt16: 2}
+	buf := breeze.NewBuffer(256)
+	breeze.WriteValue(buf, msg)
+	data, err := breeze.ToJSON(buf)
+	if err != nil || string(data) != `{"enumMap":{"1":"E1"},"myInt16":2}` {
+		t.Errorf("wrong json. err:%v, json:%s", err, data)
+	}
+	b, err := breeze.FromJSON(data, msg.GetSchema())
+	if err != nil {
+		t.Fatalf("from json fail. err:%v", err)
+	}
+	result := &NestedMsg{}
+	if _, err = breeze.ReadValue(breeze.CreateBuffer(b), result); err != nil || !reflect.DeepEqual(msg, result) {
+		t.Errorf("wrong message from json. err:%v, message:%+v", err, result)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	sub := &TestSubMsg{MyMap1: map[string][]byte{"b": []byte("1"), "a": []byte("2"), "c": nil}, MyMap2: map[int32][]int32{3: {1}, -1: {}, 2: {2}}}
+	msg := &NestedMsg{
+		NestedMap: map[string]map[int64][]*TestSubMsg{"b": {2: {sub}, 1: {sub}}, "a": {}, "c": {-3: {}}},
+		FloatMaps: []map[string]float64{{"y": 1, "x": 2}},
+		TestMsg:   &TestMsg{MyMap: map[string]*TestSubMsg{"k2": sub, "k1": sub, "k3": sub}},
+	}
+	expect := breeze.NewBuffer(256)
+	expect.SetCanonical(true)
+	if err := breeze.WriteValue(expect, msg); err != nil {
+		t.Fatalf("write fail. err:%v", err)
+	}
+	for i := 0; i < 10; i++ {
+		buf := breeze.NewBuffer(256)
+		buf.SetCanonical(true)
+		breeze.WriteValue(buf, msg)
+		if !reflect.DeepEqual(expect.Bytes(), buf.Bytes()) {
+			t.Fatalf("canonical bytes should be identical")
+		}
+	}
+	result := &NestedMsg{}
+	if _, err := breeze.ReadValue(breeze.CreateBuffer(expect.Bytes()), result); err != nil || len(result.NestedMap["b"]) != 2 {
+		t.Errorf("wrong canonical message. err:%v, message:%+v", err, result)
+	}
+}
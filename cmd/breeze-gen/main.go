@@ -0,0 +1,112 @@
+/*
+Command breeze-gen generates go codes of breeze messages and enums from breeze schema files (.breeze).
+
+Usage:
+
+	breeze-gen [-package name] [-o file | -dir dir] file.breeze...
+
+The generated messages implement breeze.Message, and the generated enums implement breeze.Enum.
+The schemas of them are registered by breeze.RegisterMessage and breeze.RegisterEnum in init().
+Each schema file is generated into a go file with suffix .breeze.go, such as testmsg.breeze -> testmsg.breeze.go.
+All the schema files in one command are generated into same go package, so they can reference each other.
+
+The go package name is decided by the first one of: the -package flag, $GOPACKAGE set by go generate,
+the go_package option in schema file and the last part of the schema package.
+It can be used with go generate:
+
+	//go:generate go run github.com/weibreeze/breeze-go/cmd/breeze-gen testmsg.breeze
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/weibreeze/breeze-go/idl"
+)
+
+// suffix of generated go files
+const generatedFileSuffix = ".breeze.go"
+
+var (
+	pkgName = flag.String("package", "", "go package name of generated codes")
+	output  = flag.String("o", "", "output file, only for single schema file")
+	dir     = flag.String("dir", ".", "output directory")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: breeze-gen [-package name] [-o file | -dir dir] file.breeze...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output != "" && flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "breeze-gen: -o can only be used with single schema file")
+		os.Exit(2)
+	}
+	if err := run(flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "breeze-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(paths []string) error {
+	files := make([]*idl.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := idl.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	pkg := packageName(files[0])
+	if pkg == "" {
+		return fmt.Errorf("can not decide go package name of %s, use -package to specify it", files[0].Name)
+	}
+	g, err := newGenerator(pkg, files)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		src, err := g.generate(f)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(outputPath(f.Name), src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageName get the go package name of generated codes
+func packageName(f *idl.File) string {
+	name := *pkgName
+	if name == "" {
+		name = os.Getenv("GOPACKAGE")
+	}
+	if name == "" {
+		name = f.Options["go_package"]
+	}
+	if name == "" {
+		name = f.Package
+	}
+	name = name[strings.LastIndexAny(name, "/.")+1:]
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// outputPath get the output file of schema file
+func outputPath(name string) string {
+	if *output != "" {
+		return *output
+	}
+	return filepath.Join(*dir, strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))+generatedFileSuffix)
+}
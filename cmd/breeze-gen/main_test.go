@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/weibreeze/breeze-go/idl"
+)
+
+func TestPackageName(t *testing.T) {
+	tests := []struct {
+		flag, env, option, pkg string
+		expect                 string
+	}{
+		{"p1", "p2", "p3", "a.p4", "p1"},
+		{"", "p2", "p3", "a.p4", "p2"},
+		{"", "", "github.com/a/p-3", "a.p4", "p_3"},
+		{"", "", "", "a.p4", "p4"},
+		{"", "", "", "", ""},
+	}
+	defer func(name string) { *pkgName = name }(*pkgName)
+	if env, ok := os.LookupEnv("GOPACKAGE"); ok {
+		defer os.Setenv("GOPACKAGE", env)
+	} else {
+		defer os.Unsetenv("GOPACKAGE")
+	}
+	for _, test := range tests {
+		*pkgName = test.flag
+		os.Setenv("GOPACKAGE", test.env)
+		f := &idl.File{Package: test.pkg, Options: map[string]string{"go_package": test.option}}
+		if name := packageName(f); name != test.expect {
+			t.Errorf("wrong package name. expect:%s, real:%s", test.expect, name)
+		}
+	}
+}
@@ -1,17 +1,17 @@
-// +build go1.12
+// +build go1.12,!go1.18
 
 package breeze
 
 import "reflect"
 
-func rangeMap(buf *Buffer, v reflect.Value) (err error) {
+func rangeMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
 	iter := v.MapRange()
 	for iter.Next() {
-		err = writeReflectValue(buf, iter.Key(), true)
+		err = key.encode(buf, iter.Key(), true)
 		if err != nil {
 			return err
 		}
-		err = writeReflectValue(buf, iter.Value(), true)
+		err = value.encode(buf, iter.Value(), true)
 		if err != nil {
 			return err
 		}
@@ -19,23 +19,27 @@ func rangeMap(buf *Buffer, v reflect.Value) (err error) {
 	return nil
 }
 
-func rangePackedMap(buf *Buffer, v reflect.Value) {
+func rangePackedMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
 	iter := v.MapRange()
-	var err error
 	first := true
 	for iter.Next() {
 		if first {
-			writeType(buf, iter.Key())
-			writeType(buf, iter.Value())
+			if err = key.writeType(buf, iter.Key()); err != nil {
+				return err
+			}
+			if err = value.writeType(buf, iter.Value()); err != nil {
+				return err
+			}
 			first = false
 		}
-		err = writeReflectValue(buf, iter.Key(), false)
+		err = key.encode(buf, iter.Key(), false)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		err = writeReflectValue(buf, iter.Value(), false)
+		err = value.encode(buf, iter.Value(), false)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
+	return nil
 }
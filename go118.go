@@ -0,0 +1,57 @@
+// +build go1.18
+
+package breeze
+
+import "reflect"
+
+// since go1.18, the key and value of map iterator can be set into reusable values to avoid allocations
+
+func rangeMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
+	iter := v.MapRange()
+	pool, entry := getMapEntry(v.Type())
+	defer putMapEntry(pool, entry)
+	k, e := entry.key, entry.value
+	for iter.Next() {
+		k.SetIterKey(iter)
+		e.SetIterValue(iter)
+		err = key.encode(buf, k, true)
+		if err != nil {
+			return err
+		}
+		err = value.encode(buf, e, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rangePackedMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
+	iter := v.MapRange()
+	pool, entry := getMapEntry(v.Type())
+	defer putMapEntry(pool, entry)
+	k, e := entry.key, entry.value
+	first := true
+	for iter.Next() {
+		k.SetIterKey(iter)
+		e.SetIterValue(iter)
+		if first {
+			if err = key.writeType(buf, k); err != nil {
+				return err
+			}
+			if err = value.writeType(buf, e); err != nil {
+				return err
+			}
+			first = false
+		}
+		err = key.encode(buf, k, false)
+		if err != nil {
+			return err
+		}
+		err = value.encode(buf, e, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
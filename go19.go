@@ -6,14 +6,14 @@ import (
 	"reflect"
 )
 
-func rangeMap(buf *Buffer, v reflect.Value) (err error) {
+func rangeMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
 	ks := v.MapKeys()
 	for _, k := range ks {
-		err = writeReflectValue(buf, k, true)
+		err = key.encode(buf, k, true)
 		if err != nil {
 			return err
 		}
-		err = writeReflectValue(buf, v.MapIndex(k), true)
+		err = value.encode(buf, v.MapIndex(k), true)
 		if err != nil {
 			return err
 		}
@@ -21,23 +21,28 @@ func rangeMap(buf *Buffer, v reflect.Value) (err error) {
 	return err
 }
 
-func rangePackedMap(buf *Buffer, v reflect.Value) {
+func rangePackedMap(buf *Buffer, v reflect.Value, key *codec, value *codec) (err error) {
 	ks := v.MapKeys()
-	var err error
 	first := true
 	for _, k := range ks {
+		mv := v.MapIndex(k)
 		if first {
-			writeType(buf, k)
-			writeType(buf, v.MapIndex(k))
+			if err = key.writeType(buf, k); err != nil {
+				return err
+			}
+			if err = value.writeType(buf, mv); err != nil {
+				return err
+			}
 			first = false
 		}
-		err = writeReflectValue(buf, k, false)
+		err = key.encode(buf, k, false)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		err = writeReflectValue(buf, v.MapIndex(k), false)
+		err = value.encode(buf, mv, false)
 		if err != nil {
-			panic(err)
+			return err
 		}
 	}
+	return nil
 }
@@ -0,0 +1,197 @@
+/*
+Package idl parses breeze schema files (.breeze) into AST and breeze schemas.
+
+A breeze schema file looks like:
+
+	option go_package = breeze;
+	package motan;
+
+	message TestMsg(alias=Test) {
+		int32 myInt = 1;
+		map<string, TestSubMsg> myMap = 2;
+		array<MyEnum> enumArray = 3;
+	}
+
+	enum MyEnum {
+		E1 = 1;
+		E2 = 2;
+	}
+
+basic types are bool, string, byte, bytes, int16, int32, int64, float32 and float64,
+container types are map<K, V> and array<T>, other types are messages or enums.
+*/
+package idl
+
+import (
+	"strconv"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+// basic types of breeze schema
+var basicTypes = map[string]bool{
+	"bool": true, "string": true, "byte": true, "bytes": true, "int16": true,
+	"int32": true, "int64": true, "float32": true, "float64": true,
+}
+
+// container types of breeze schema
+const (
+	MapType   = "map"
+	ArrayType = "array"
+)
+
+// enum schema field, enum is written as a message with one int32 field
+const (
+	EnumFieldIndex = 1
+	EnumFieldName  = "enumNumber"
+)
+
+// Position is a position in schema file
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// Error is a parse error with position
+type Error struct {
+	File string
+	Pos  Position
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return e.File + ":" + e.Pos.String() + ": " + e.Msg
+}
+
+// File is the AST of a schema file
+type File struct {
+	Name     string // file name
+	Package  string
+	Options  map[string]string
+	Messages []*Message
+	Enums    []*Enum
+}
+
+// Message is a message definition
+type Message struct {
+	Pos     Position
+	Name    string // name without package
+	Alias   string
+	Options map[string]string
+	Fields  []*Field
+}
+
+// Field is a field of message
+type Field struct {
+	Pos   Position
+	Index int
+	Name  string
+	Type  *Type
+}
+
+// Type is a field type, such as int32, TestSubMsg and map<string, array<int32>>
+type Type struct {
+	Pos   Position
+	Name  string // basic type name, message or enum name, MapType or ArrayType
+	Key   *Type  // key type of map
+	Value *Type  // value type of map or element type of array
+}
+
+// IsBasic check whether the type is a basic type
+func (t *Type) IsBasic() bool {
+	return basicTypes[t.Name]
+}
+
+// IsContainer check whether the type is map or array
+func (t *Type) IsContainer() bool {
+	return t.Name == MapType || t.Name == ArrayType
+}
+
+// String get the type string used in breeze.Field, such as map<string, TestSubMsg>
+func (t *Type) String() string {
+	switch t.Name {
+	case MapType:
+		return MapType + "<" + t.Key.String() + ", " + t.Value.String() + ">"
+	case ArrayType:
+		return ArrayType + "<" + t.Value.String() + ">"
+	}
+	return t.Name
+}
+
+// Enum is an enum definition
+type Enum struct {
+	Pos     Position
+	Name    string
+	Alias   string
+	Options map[string]string
+	Values  []*EnumValue
+}
+
+// EnumValue is a value of enum
+type EnumValue struct {
+	Pos    Position
+	Name   string
+	Number int
+}
+
+// FullName get the message or enum name with package, such as motan.TestMsg
+func (f *File) FullName(name string) string {
+	return fullName(f.Package, name)
+}
+
+// Schema get the breeze schema of a message or enum by name without package, return nil if not found
+func (f *File) Schema(name string) *breeze.Schema {
+	for _, m := range f.Messages {
+		if m.Name == name {
+			return m.Schema(f.Package)
+		}
+	}
+	for _, e := range f.Enums {
+		if e.Name == name {
+			return e.Schema(f.Package)
+		}
+	}
+	return nil
+}
+
+// Schemas get the breeze schemas of all messages and enums in file
+func (f *File) Schemas() []*breeze.Schema {
+	schemas := make([]*breeze.Schema, 0, len(f.Messages)+len(f.Enums))
+	for _, m := range f.Messages {
+		schemas = append(schemas, m.Schema(f.Package))
+	}
+	for _, e := range f.Enums {
+		schemas = append(schemas, e.Schema(f.Package))
+	}
+	return schemas
+}
+
+// Schema get the breeze schema of message
+func (m *Message) Schema(pkg string) *breeze.Schema {
+	schema := &breeze.Schema{Name: fullName(pkg, m.Name), Alias: m.Alias}
+	for _, field := range m.Fields {
+		schema.PutFields(&breeze.Field{Index: field.Index, Name: field.Name, Type: field.Type.String()})
+	}
+	return schema
+}
+
+// Schema get the breeze schema of enum
+func (e *Enum) Schema(pkg string) *breeze.Schema {
+	schema := &breeze.Schema{Name: fullName(pkg, e.Name), Alias: e.Alias}
+	schema.PutFields(&breeze.Field{Index: EnumFieldIndex, Name: EnumFieldName, Type: "int32"})
+	for _, v := range e.Values {
+		schema.PutEnumValue(v.Number, v.Name)
+	}
+	return schema
+}
+
+func fullName(pkg string, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
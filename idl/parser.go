@@ -0,0 +1,450 @@
+package idl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// token kinds
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+type token struct {
+	kind int
+	text string
+	pos  Position
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of file"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+type parser struct {
+	name string
+	src  []byte
+	off  int
+	line int
+	col  int
+	tok  token // current token
+}
+
+// ParseFile read and parse a schema file
+func ParseFile(path string) (*File, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse parse the source of a schema file, name is the file name used in errors
+func Parse(name string, src []byte) (*File, error) {
+	p := &parser{name: name, src: src, line: 1, col: 1}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	f := &File{Name: name, Options: make(map[string]string)}
+	names := make(map[string]Position)
+	for p.tok.kind != tokenEOF {
+		start := p.tok
+		if start.kind != tokenIdent {
+			return nil, p.errorf(start.pos, "unexpected %s, expect option, package, message or enum", start)
+		}
+		switch start.text {
+		case "option":
+			if err := p.parseOption(f.Options); err != nil {
+				return nil, err
+			}
+		case "package":
+			if f.Package != "" {
+				return nil, p.errorf(start.pos, "duplicate package")
+			}
+			pkg, err := p.parsePackage()
+			if err != nil {
+				return nil, err
+			}
+			f.Package = pkg
+		case "message":
+			m, err := p.parseMessage()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.checkName(names, m.Name, m.Pos); err != nil {
+				return nil, err
+			}
+			f.Messages = append(f.Messages, m)
+		case "enum":
+			e, err := p.parseEnum()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.checkName(names, e.Name, e.Pos); err != nil {
+				return nil, err
+			}
+			f.Enums = append(f.Enums, e)
+		default:
+			return nil, p.errorf(start.pos, "unexpected %s, expect option, package, message or enum", start)
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) checkName(names map[string]Position, name string, pos Position) error {
+	if old, ok := names[name]; ok {
+		return p.errorf(pos, "duplicate definition of %s, previous definition at %s", name, old)
+	}
+	names[name] = pos
+	return nil
+}
+
+// option name = value;
+func (p *parser) parseOption(options map[string]string) error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	name, err := p.expectIdent()
+	if err != nil {
+		return err
+	}
+	if err = p.expect("="); err != nil {
+		return err
+	}
+	value, err := p.expectValue()
+	if err != nil {
+		return err
+	}
+	options[name] = value
+	return p.expect(";")
+}
+
+// package name;
+func (p *parser) parsePackage() (string, error) {
+	if err := p.next(); err != nil {
+		return "", err
+	}
+	pkg, err := p.expectIdent()
+	if err != nil {
+		return "", err
+	}
+	return pkg, p.expect(";")
+}
+
+// (name=value, name=value)
+func (p *parser) parseDefinitionOptions() (map[string]string, error) {
+	options := make(map[string]string)
+	if !p.is("(") {
+		return options, nil
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for {
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("="); err != nil {
+			return nil, err
+		}
+		if options[name], err = p.expectValue(); err != nil {
+			return nil, err
+		}
+		if p.is(")") {
+			return options, p.next()
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// message Name(options) { type name = index; }
+func (p *parser) parseMessage() (*Message, error) {
+	m := &Message{Pos: p.tok.pos}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var err error
+	if m.Name, err = p.expectIdent(); err != nil {
+		return nil, err
+	}
+	if m.Options, err = p.parseDefinitionOptions(); err != nil {
+		return nil, err
+	}
+	m.Alias = m.Options["alias"]
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	indexes := make(map[int]bool)
+	names := make(map[string]bool)
+	for !p.is("}") {
+		field := &Field{Pos: p.tok.pos}
+		if field.Type, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		namePos := p.tok.pos
+		if field.Name, err = p.expectIdent(); err != nil {
+			return nil, err
+		}
+		if names[field.Name] {
+			return nil, p.errorf(namePos, "duplicate field name %s in message %s", field.Name, m.Name)
+		}
+		if err = p.expect("="); err != nil {
+			return nil, err
+		}
+		indexPos := p.tok.pos
+		if field.Index, err = p.expectNumber(); err != nil {
+			return nil, err
+		}
+		if field.Index < 0 {
+			return nil, p.errorf(indexPos, "field index can not be negative")
+		}
+		if indexes[field.Index] {
+			return nil, p.errorf(indexPos, "duplicate field index %d in message %s", field.Index, m.Name)
+		}
+		if err = p.expect(";"); err != nil {
+			return nil, err
+		}
+		indexes[field.Index] = true
+		names[field.Name] = true
+		m.Fields = append(m.Fields, field)
+	}
+	return m, p.next()
+}
+
+// int32, TestSubMsg, map<K, V>, array<T>
+func (p *parser) parseType() (*Type, error) {
+	t := &Type{Pos: p.tok.pos}
+	var err error
+	if t.Name, err = p.expectIdent(); err != nil {
+		return nil, err
+	}
+	if !t.IsContainer() {
+		return t, nil
+	}
+	if err = p.expect("<"); err != nil {
+		return nil, err
+	}
+	if t.Name == MapType {
+		if t.Key, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if t.Key.IsContainer() {
+			return nil, p.errorf(t.Key.Pos, "map key can not be %s", t.Key.Name)
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+	if t.Value, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	return t, p.expect(">")
+}
+
+// enum Name(options) { NAME = number; }
+func (p *parser) parseEnum() (*Enum, error) {
+	e := &Enum{Pos: p.tok.pos}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var err error
+	if e.Name, err = p.expectIdent(); err != nil {
+		return nil, err
+	}
+	if e.Options, err = p.parseDefinitionOptions(); err != nil {
+		return nil, err
+	}
+	e.Alias = e.Options["alias"]
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	numbers := make(map[int]bool)
+	names := make(map[string]bool)
+	for !p.is("}") {
+		value := &EnumValue{Pos: p.tok.pos}
+		if value.Name, err = p.expectIdent(); err != nil {
+			return nil, err
+		}
+		if names[value.Name] {
+			return nil, p.errorf(value.Pos, "duplicate enum value %s in enum %s", value.Name, e.Name)
+		}
+		if err = p.expect("="); err != nil {
+			return nil, err
+		}
+		numberPos := p.tok.pos
+		if value.Number, err = p.expectNumber(); err != nil {
+			return nil, err
+		}
+		if numbers[value.Number] {
+			return nil, p.errorf(numberPos, "duplicate enum number %d in enum %s", value.Number, e.Name)
+		}
+		if err = p.expect(";"); err != nil {
+			return nil, err
+		}
+		names[value.Name] = true
+		numbers[value.Number] = true
+		e.Values = append(e.Values, value)
+	}
+	return e, p.next()
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.text == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.is(punct) {
+		return p.errorf(p.tok.pos, "unexpected %s, expect '%s'", p.tok, punct)
+	}
+	return p.next()
+}
+
+func (p *parser) expectIdent() (string, error) {
+	if p.tok.kind != tokenIdent {
+		return "", p.errorf(p.tok.pos, "unexpected %s, expect identifier", p.tok)
+	}
+	text := p.tok.text
+	return text, p.next()
+}
+
+func (p *parser) expectNumber() (int, error) {
+	if p.tok.kind != tokenNumber {
+		return 0, p.errorf(p.tok.pos, "unexpected %s, expect number", p.tok)
+	}
+	n, err := strconv.Atoi(p.tok.text)
+	if err != nil {
+		return 0, p.errorf(p.tok.pos, "invalid number %s", p.tok.text)
+	}
+	return n, p.next()
+}
+
+// option value can be an identifier, a number or a quoted string
+func (p *parser) expectValue() (string, error) {
+	if p.tok.kind != tokenIdent && p.tok.kind != tokenNumber && p.tok.kind != tokenString {
+		return "", p.errorf(p.tok.pos, "unexpected %s, expect option value", p.tok)
+	}
+	text := p.tok.text
+	return text, p.next()
+}
+
+func (p *parser) errorf(pos Position, format string, args ...interface{}) error {
+	return &Error{File: p.name, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next scan the next token into p.tok
+func (p *parser) next() error {
+	if err := p.skipSpaceAndComments(); err != nil {
+		return err
+	}
+	pos := Position{Line: p.line, Column: p.col}
+	if p.off >= len(p.src) {
+		p.tok = token{kind: tokenEOF, pos: pos}
+		return nil
+	}
+	c := p.src[p.off]
+	start := p.off
+	switch {
+	case isLetter(c):
+		for p.off < len(p.src) && (isLetter(p.src[p.off]) || isDigit(p.src[p.off]) || p.src[p.off] == '.') {
+			p.advance()
+		}
+		p.tok = token{kind: tokenIdent, text: string(p.src[start:p.off]), pos: pos}
+	case isDigit(c) || c == '-':
+		p.advance()
+		for p.off < len(p.src) && isDigit(p.src[p.off]) {
+			p.advance()
+		}
+		p.tok = token{kind: tokenNumber, text: string(p.src[start:p.off]), pos: pos}
+	case c == '"':
+		p.advance()
+		for p.off < len(p.src) && p.src[p.off] != '"' && p.src[p.off] != '\n' {
+			if p.src[p.off] == '\\' && p.off+1 < len(p.src) {
+				p.advance()
+			}
+			p.advance()
+		}
+		if p.off >= len(p.src) || p.src[p.off] != '"' {
+			return p.errorf(pos, "unterminated string")
+		}
+		p.advance()
+		text, err := strconv.Unquote(string(p.src[start:p.off]))
+		if err != nil {
+			return p.errorf(pos, "invalid string %s", p.src[start:p.off])
+		}
+		p.tok = token{kind: tokenString, text: text, pos: pos}
+	case isPunct(c):
+		p.advance()
+		p.tok = token{kind: tokenPunct, text: string(c), pos: pos}
+	default:
+		return p.errorf(pos, "unexpected character %q", c)
+	}
+	return nil
+}
+
+func (p *parser) skipSpaceAndComments() error {
+	for p.off < len(p.src) {
+		c := p.src[p.off]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.advance()
+		case c == '/' && p.off+1 < len(p.src) && p.src[p.off+1] == '/':
+			for p.off < len(p.src) && p.src[p.off] != '\n' {
+				p.advance()
+			}
+		case c == '/' && p.off+1 < len(p.src) && p.src[p.off+1] == '*':
+			pos := Position{Line: p.line, Column: p.col}
+			p.advance()
+			p.advance()
+			for p.off < len(p.src) && !(p.src[p.off] == '*' && p.off+1 < len(p.src) && p.src[p.off+1] == '/') {
+				p.advance()
+			}
+			if p.off >= len(p.src) {
+				return p.errorf(pos, "unterminated comment")
+			}
+			p.advance()
+			p.advance()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) advance() {
+	if p.src[p.off] == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+	p.off++
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isPunct(c byte) bool {
+	switch c {
+	case '{', '}', '(', ')', '<', '>', ',', ';', '=':
+		return true
+	}
+	return false
+}
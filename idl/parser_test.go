@@ -0,0 +1,87 @@
+package idl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+func TestParseFile(t *testing.T) {
+	f, err := ParseFile("testdata/testmsg.breeze")
+	if err != nil {
+		t.Fatalf("parse fail. err:%v", err)
+	}
+	if f.Package != "motan" || f.Options["go_package"] != "breeze" || f.Options["java_package"] != "com.weibo.breeze.test.message" {
+		t.Errorf("wrong package or options. package:%s, options:%v", f.Package, f.Options)
+	}
+	if len(f.Messages) != 2 || len(f.Enums) != 1 {
+		t.Fatalf("wrong definition count. messages:%d, enums:%d", len(f.Messages), len(f.Enums))
+	}
+	enum := f.Enums[0]
+	if enum.Name != "MyEnum" || len(enum.Values) != 3 || enum.Values[2].Name != "E3" || enum.Values[2].Number != 3 {
+		t.Errorf("wrong enum: %+v", enum)
+	}
+	myMap2 := f.Messages[1].Fields[8]
+	if myMap2.Type.Name != MapType || myMap2.Type.Key.Name != "int32" || myMap2.Type.Value.Value.Name != "int32" || myMap2.Pos.Line != 26 || myMap2.Pos.Column != 5 {
+		t.Errorf("wrong field: %+v, type:%s", myMap2, myMap2.Type)
+	}
+
+	// schemas are same as the generated messages
+	expects := []*breeze.Schema{(&breeze.TestMsg{}).GetSchema(), (&breeze.TestSubMsg{}).GetSchema(), breeze.MyEnumE1.GetSchema()}
+	schemas := f.Schemas()
+	if len(schemas) != len(expects) {
+		t.Fatalf("wrong schema count: %d", len(schemas))
+	}
+	for i, expect := range expects {
+		if schemas[i].Name != expect.Name || schemas[i].Alias != expect.Alias || !reflect.DeepEqual(schemas[i].GetFields(), expect.GetFields()) {
+			t.Errorf("wrong schema. expect:%+v, real:%+v", expect.GetFields(), schemas[i].GetFields())
+		}
+	}
+	if name, ok := schemas[2].GetEnumName(3); !ok || name != "E3" {
+		t.Errorf("wrong enum value of schema: %s", name)
+	}
+	if f.Schema("TestSubMsg").Name != "motan.TestSubMsg" || f.Schema("NotExist") != nil {
+		t.Errorf("wrong schema by name")
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	src := `package a.b; message M(alias=AM, x="y") { array<map<string, int64>> m = 0; } enum E(alias=AE) { A = -1; }`
+	f, err := Parse("options.breeze", []byte(src))
+	if err != nil {
+		t.Fatalf("parse fail. err:%v", err)
+	}
+	m := f.Messages[0]
+	if m.Alias != "AM" || m.Options["x"] != "y" || m.Fields[0].Type.String() != "array<map<string, int64>>" {
+		t.Errorf("wrong message: %+v", m)
+	}
+	if f.Enums[0].Alias != "AE" || f.Enums[0].Values[0].Number != -1 || f.Schema("E").Name != "a.b.E" {
+		t.Errorf("wrong enum: %+v", f.Enums[0])
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		src string
+		pos Position
+	}{
+		{"message M {\n  int32 a = 1\n}", Position{3, 1}},
+		{"message M {\n  int32 a = 1;\n  string a = 2;\n}", Position{3, 10}},
+		{"message M {\n  int32 a = 1;\n  string b = 1;\n}", Position{3, 14}},
+		{"message M {\n  map<array<int32>, int32> a = 1;\n}", Position{2, 7}},
+		{"message M {}\nenum M { A = 1; }", Position{2, 1}},
+		{"enum E {\n  A = 1;\n  B = 1;\n}", Position{3, 7}},
+		{"service S {}", Position{1, 1}},
+		{"package p;\n/* comment", Position{2, 1}},
+		{"option a = \"b;", Position{1, 12}},
+		{"message M {\n  int32 a = #;\n}", Position{2, 13}},
+	}
+	for _, c := range cases {
+		_, err := Parse("error.breeze", []byte(c.src))
+		e, ok := err.(*Error)
+		if !ok || e.Pos != c.pos || e.File != "error.breeze" {
+			t.Errorf("wrong parse error. src:%q, expect pos:%s, err:%v", c.src, c.pos, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package breeze
 
 import (
 	"errors"
+	"sort"
 	"strconv"
 )
 
@@ -35,7 +36,7 @@ func (m MyEnum) ReadEnum(buf *Buffer, asAddr bool) (Enum, error) {
 		case 1:
 			err = ReadInt32(buf, &number)
 		default: //skip unknown field
-			_, err = ReadValue(buf, nil)
+			err = SkipValue(buf)
 		}
 		return err
 	})
@@ -79,6 +80,7 @@ type TestMsg struct {
 	SubMsg    *TestSubMsg
 	MyEnum    *MyEnum
 	EnumArray []*MyEnum
+	UnknownFields
 }
 
 func (t *TestMsg) WriteTo(buf *Buffer) error {
@@ -87,16 +89,26 @@ func (t *TestMsg) WriteTo(buf *Buffer) error {
 		WriteStringField(buf, 2, t.MyString)
 		if len(t.MyMap) > 0 {
 			WriteMapField(buf, 3, len(t.MyMap), func(buf *Buffer) {
-				first := true
-				for k1, v1 := range t.MyMap {
-					if first {
-						WriteStringType(buf)
-						WriteMessageType(buf, v1.GetName())
-						first = false
-					}
+				WriteStringType(buf)
+				WriteMessageType(buf, testSubMsgBreezeSchema.Name)
+				w1 := func(k1 string, v1 *TestSubMsg) {
 					WriteString(buf, k1, false)
 					v1.WriteTo(buf)
 				}
+				if buf.IsCanonical() {
+					ks1 := make([]string, 0, len(t.MyMap))
+					for k1 := range t.MyMap {
+						ks1 = append(ks1, k1)
+					}
+					sort.Slice(ks1, func(i, j int) bool { return ks1[i] < ks1[j] })
+					for _, k1 := range ks1 {
+						w1(k1, t.MyMap[k1])
+					}
+				} else {
+					for k1, v1 := range t.MyMap {
+						w1(k1, v1)
+					}
+				}
 			})
 		}
 		if len(t.MyArray) > 0 {
@@ -129,10 +141,12 @@ func (t *TestMsg) WriteTo(buf *Buffer) error {
 				}
 			})
 		}
+		t.WriteUnknownFields(buf)
 	})
 }
 
 func (t *TestMsg) ReadFrom(buf *Buffer) error {
+	t.ClearUnknownFields()
 	return ReadMessageField(buf, func(buf *Buffer, index int) (err error) {
 		switch index {
 		case 1:
@@ -140,7 +154,7 @@ func (t *TestMsg) ReadFrom(buf *Buffer) error {
 		case 2:
 			err = ReadString(buf, &t.MyString)
 		case 3:
-			size, err := ReadPackedSize(buf, true)
+			size, err := ReadPackedMapSize(buf, true)
 			if err != nil {
 				return err
 			}
@@ -159,7 +173,7 @@ func (t *TestMsg) ReadFrom(buf *Buffer) error {
 			})
 			return err
 		case 4:
-			size, err := ReadPackedSize(buf, true)
+			size, err := ReadPackedArraySize(buf, true)
 			if err != nil {
 				return err
 			}
@@ -183,7 +197,7 @@ func (t *TestMsg) ReadFrom(buf *Buffer) error {
 				t.MyEnum = result.(*MyEnum)
 			}
 		case 7:
-			size, err := ReadPackedSize(buf, true)
+			size, err := ReadPackedArraySize(buf, true)
 			if err != nil {
 				return err
 			}
@@ -197,8 +211,8 @@ func (t *TestMsg) ReadFrom(buf *Buffer) error {
 				return err
 			})
 			return err
-		default: //skip unknown field
-			_, err = ReadValue(buf, nil)
+		default: //keep unknown field
+			err = t.ReadUnknownField(buf, index)
 		}
 		return err
 	})
@@ -228,6 +242,7 @@ type TestSubMsg struct {
 	MyMap2    map[int32][]int32
 	MyArray   []int32
 	MyBool    bool
+	UnknownFields
 }
 
 func (t *TestSubMsg) WriteTo(buf *Buffer) error {
@@ -243,22 +258,50 @@ func (t *TestSubMsg) WriteTo(buf *Buffer) error {
 			WriteMapField(buf, 8, len(t.MyMap1), func(buf *Buffer) {
 				WriteStringType(buf)
 				WriteBytesType(buf)
-				for k1, v1 := range t.MyMap1 {
+				w1 := func(k1 string, v1 []byte) {
 					WriteString(buf, k1, false)
 					WriteBytes(buf, v1, false)
 				}
+				if buf.IsCanonical() {
+					ks1 := make([]string, 0, len(t.MyMap1))
+					for k1 := range t.MyMap1 {
+						ks1 = append(ks1, k1)
+					}
+					sort.Slice(ks1, func(i, j int) bool { return ks1[i] < ks1[j] })
+					for _, k1 := range ks1 {
+						w1(k1, t.MyMap1[k1])
+					}
+				} else {
+					for k1, v1 := range t.MyMap1 {
+						w1(k1, v1)
+					}
+				}
 			})
 		}
 		if len(t.MyMap2) > 0 {
 			WriteMapField(buf, 9, len(t.MyMap2), func(buf *Buffer) {
 				WriteInt32Type(buf)
 				WritePackedArrayType(buf)
-				for k1, v1 := range t.MyMap2 {
+				w1 := func(k1 int32, v1 []int32) {
 					WriteInt32(buf, k1, false)
 					WritePackedArray(buf, false, len(v1), func(buf *Buffer) {
 						WriteInt32ArrayElems(buf, v1)
 					})
 				}
+				if buf.IsCanonical() {
+					ks1 := make([]int32, 0, len(t.MyMap2))
+					for k1 := range t.MyMap2 {
+						ks1 = append(ks1, k1)
+					}
+					sort.Slice(ks1, func(i, j int) bool { return ks1[i] < ks1[j] })
+					for _, k1 := range ks1 {
+						w1(k1, t.MyMap2[k1])
+					}
+				} else {
+					for k1, v1 := range t.MyMap2 {
+						w1(k1, v1)
+					}
+				}
 			})
 		}
 		if len(t.MyArray) > 0 {
@@ -267,10 +310,12 @@ func (t *TestSubMsg) WriteTo(buf *Buffer) error {
 			})
 		}
 		WriteBoolField(buf, 11, t.MyBool)
+		t.WriteUnknownFields(buf)
 	})
 }
 
 func (t *TestSubMsg) ReadFrom(buf *Buffer) error {
+	t.ClearUnknownFields()
 	return ReadMessageField(buf, func(buf *Buffer, index int) (err error) {
 		switch index {
 		case 1:
@@ -288,7 +333,7 @@ func (t *TestSubMsg) ReadFrom(buf *Buffer) error {
 		case 7:
 			err = ReadBytes(buf, &t.MyBytes)
 		case 8:
-			size, err := ReadPackedSize(buf, true)
+			size, err := ReadPackedMapSize(buf, true)
 			if err != nil {
 				return err
 			}
@@ -306,7 +351,7 @@ func (t *TestSubMsg) ReadFrom(buf *Buffer) error {
 			})
 			return err
 		case 9:
-			size, err := ReadPackedSize(buf, true)
+			size, err := ReadPackedMapSize(buf, true)
 			if err != nil {
 				return err
 			}
@@ -327,8 +372,8 @@ func (t *TestSubMsg) ReadFrom(buf *Buffer) error {
 			t.MyArray, err = ReadInt32Array(buf, true)
 		case 11:
 			err = ReadBool(buf, &t.MyBool)
-		default: //skip unknown field
-			_, err = ReadValue(buf, nil)
+		default: //keep unknown field
+			err = t.ReadUnknownField(buf, index)
 		}
 		return err
 	})